@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/analysis"
+	"github.com/dazfuller/azcosts/internal/model"
+	"github.com/dazfuller/azcosts/internal/sqlite"
+	"log"
+	"os"
+	"strings"
+)
+
+func validateAnomaliesFlags(flags *flag.FlagSet) {
+	formatLower := strings.ToLower(format)
+	if formatLower != TextFormat && formatLower != JsonFormat {
+		displayErrorMessage("a valid format must be specified", flags)
+	}
+
+	if anomalyMonths < 2 {
+		displayErrorMessage("number of months must be at least 2 so that there is history to compare against", flags)
+	}
+
+	if stdDevThreshold < 0 || percentThreshold < 0 || minimumCost < 0 {
+		displayErrorMessage("thresholds cannot be negative", flags)
+	}
+}
+
+func displayAnomalies() error {
+	db, err := getCostManagementStore()
+	if err != nil {
+		return err
+	}
+	defer func(db *sqlite.CostManagementStore) {
+		err := db.Close()
+		if err != nil {
+			log.Printf("Unable to close data store: %e", err)
+		}
+	}(db)
+
+	summary, err := db.GenerateSummaryByResourceGroup(anomalyMonths, nil)
+	if err != nil {
+		return err
+	}
+
+	anomalies := analysis.DetectAnomalies(summary, analysis.AnomalyOptions{
+		StdDevThreshold:  stdDevThreshold,
+		PercentThreshold: percentThreshold,
+		MinimumCost:      minimumCost,
+	})
+
+	if len(anomalies) > 0 {
+		exitCode = 2
+	}
+
+	if strings.ToLower(format) == JsonFormat {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(anomalies)
+	}
+
+	if len(anomalies) == 0 {
+		fmt.Println("No anomalies found")
+		return nil
+	}
+
+	fmt.Printf("%-8s %-50s %-30s %-7s %12s %12s %9s\n", "Kind", "Resource Group", "Subscription", "Period", "Cost", "Mean", "Change")
+	fmt.Printf("%-8s %-50s %-30s %-7s %12s %12s %9s\n", strings.Repeat("=", 8), strings.Repeat("=", 50), strings.Repeat("=", 30), strings.Repeat("=", 7), strings.Repeat("=", 12), strings.Repeat("=", 12), strings.Repeat("=", 9))
+
+	for _, anomaly := range anomalies {
+		name := anomaly.ResourceGroup
+		if len(name) > 50 {
+			name = name[:50]
+		}
+
+		subscription := anomaly.SubscriptionName
+		if len(subscription) > 30 {
+			subscription = subscription[:30]
+		}
+
+		change := "n/a"
+		if anomaly.Mean > 0 || anomaly.Kind == model.AnomalyVanished {
+			change = fmt.Sprintf("%.1f%%", anomaly.Change)
+		}
+
+		fmt.Printf("%-8s %-50s %-30s %-7s %12.2f %12.2f %9s\n", anomaly.Kind, name, subscription, anomaly.Period, anomaly.Cost, anomaly.Mean, change)
+	}
+
+	return nil
+}
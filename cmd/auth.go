@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/azure"
+	"slices"
+	"strings"
+)
+
+var (
+	authMethod      string
+	tenantId        string
+	clientId        string
+	certificatePath string
+)
+
+// addAuthFlags adds the flags used to select how to authenticate with Azure to a command.
+func addAuthFlags(flags *flag.FlagSet) {
+	flags.StringVar(&authMethod, "auth", azure.DefaultAuth, fmt.Sprintf(
+		"The method used to authenticate with Azure. Allowed values are '%s'", strings.Join(azure.AuthMethods, "', '")))
+	flags.StringVar(&tenantId, "tenant", "", "The id of the tenant to authenticate in, or a comma separated list of tenants to collect from")
+	flags.StringVar(&clientId, "client-id", "", "The client id of the service principal, user-assigned managed identity, or application to authenticate as")
+	flags.StringVar(&certificatePath, "certificate", "", "The path to a certificate to authenticate the service principal with instead of a client secret")
+}
+
+func validateAuthFlags(flags *flag.FlagSet) {
+	authMethod = strings.ToLower(authMethod)
+
+	if !slices.Contains(azure.AuthMethods, authMethod) {
+		displayErrorMessage(fmt.Sprintf("the auth method must be one of '%s'", strings.Join(azure.AuthMethods, "', '")), flags)
+	}
+
+	if len(certificatePath) > 0 && authMethod != azure.ServicePrincipalAuth {
+		displayErrorMessage(fmt.Sprintf("a certificate can only be used with the '%s' auth method", azure.ServicePrincipalAuth), flags)
+	}
+
+	if len(tenantId) > 0 && authMethod == azure.ManagedIdentityAuth {
+		displayErrorMessage(fmt.Sprintf("a tenant cannot be used with the '%s' auth method", azure.ManagedIdentityAuth), flags)
+	}
+
+	tenant := ""
+	if tenants := tenantIds(); len(tenants) > 0 {
+		tenant = tenants[0]
+	}
+
+	if err := useTenant(tenant); err != nil {
+		displayErrorMessage(err.Error(), flags)
+	}
+}
+
+// tenantIds returns the tenants provided using the -tenant argument, which can be a comma separated list.
+func tenantIds() []string {
+	return parseTenantIds(tenantId)
+}
+
+// parseTenantIds returns the distinct tenant ids from a comma separated list.
+func parseTenantIds(value string) []string {
+	var tenants []string
+	for _, tenant := range strings.Split(value, ",") {
+		tenant = strings.TrimSpace(tenant)
+		if len(tenant) > 0 && !slices.Contains(tenants, tenant) {
+			tenants = append(tenants, tenant)
+		}
+	}
+	return tenants
+}
+
+// useTenant sets the credential used by the Azure services to one which authenticates in the given tenant, so that
+// tokens are acquired per tenant when collecting from more than one.
+func useTenant(tenant string) error {
+	return azure.UseCredential(azure.CredentialOptions{
+		Method:          authMethod,
+		TenantId:        tenant,
+		ClientId:        clientId,
+		CertificatePath: certificatePath,
+	})
+}
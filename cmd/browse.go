@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/browse"
+	"github.com/dazfuller/azcosts/internal/sqlite"
+	"log"
+	"slices"
+	"strings"
+)
+
+var (
+	browseMonths  int
+	browseGroupBy string
+)
+
+func addBrowseFlags(flags *flag.FlagSet) {
+	flags.IntVar(&browseMonths, "months", 6, "The number of months of costs to browse")
+	flags.StringVar(&browseGroupBy, "by", ResourceGroupLevel, fmt.Sprintf(
+		"The level to summarise costs at. Allowed values are '%s', '%s', and '%s'", ResourceGroupLevel, ManagementGroupLevel, TenantLevel))
+	flags.StringVar(&tenantId, "tenant", "", "A comma separated list of tenant ids to limit the costs to")
+}
+
+func validateBrowseFlags(flags *flag.FlagSet) {
+	if browseMonths <= 0 {
+		displayErrorMessage("number of months must be greater than 0", flags)
+	}
+
+	browseGroupBy = strings.ToLower(browseGroupBy)
+	if !slices.Contains([]string{ResourceGroupLevel, ManagementGroupLevel, TenantLevel}, browseGroupBy) {
+		displayErrorMessage(fmt.Sprintf("the level must be one of '%s', '%s', or '%s'", ResourceGroupLevel, ManagementGroupLevel, TenantLevel), flags)
+	}
+}
+
+// browseCosts shows the collected costs in an interactive terminal UI.
+func browseCosts() error {
+	db, err := getCostManagementStore()
+	if err != nil {
+		return err
+	}
+	defer func(db *sqlite.CostManagementStore) {
+		err := db.Close()
+		if err != nil {
+			log.Printf("Unable to close data store: %e", err)
+		}
+	}(db)
+
+	summary, grouping, err := summarise(db, summaryOptions{
+		level:   browseGroupBy,
+		months:  browseMonths,
+		tenants: tenantIds(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return browse.Run(summary, browse.Labels{Name: grouping.Name, Parent: grouping.Parent})
+}
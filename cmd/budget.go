@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/budgets"
+	"github.com/dazfuller/azcosts/internal/formats"
+	"github.com/dazfuller/azcosts/internal/model"
+	"github.com/dazfuller/azcosts/internal/sqlite"
+	"io"
+	"log"
+	"slices"
+	"strings"
+)
+
+func validateBudgetFlags(flags *flag.FlagSet) {
+	if len(budgetPath) == 0 && !useAzureBudgets {
+		displayErrorMessage("a budget file must be specified, or the azure option used", flags)
+	}
+
+	allowedFormats := []string{
+		TextFormat,
+		CsvFormat,
+		JsonFormat,
+		ExcelFormat,
+	}
+
+	formatLower := strings.ToLower(format)
+	if !slices.Contains(allowedFormats, formatLower) {
+		displayErrorMessage("a valid format must be specified", flags)
+	}
+
+	if !useStdOut && len(outputPath) == 0 {
+		displayErrorMessage("when not writing to stdout an output path must be specified", flags)
+	}
+
+	if budgetMonths <= 0 {
+		displayErrorMessage("number of months must be greater than 0", flags)
+	}
+
+	if budgetThreshold <= 0 {
+		displayErrorMessage("threshold must be greater than 0", flags)
+	}
+}
+
+func generateBudgetReport() error {
+	var budgetDefinitions []model.Budget
+	var err error
+
+	if len(budgetPath) > 0 {
+		budgetDefinitions, err = budgets.Load(budgetPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	db, err := getCostManagementStore()
+	if err != nil {
+		return err
+	}
+	defer func(db *sqlite.CostManagementStore) {
+		err := db.Close()
+		if err != nil {
+			log.Printf("Unable to close data store: %e", err)
+		}
+	}(db)
+
+	if useAzureBudgets {
+		azureBudgets, err := db.ListBudgets()
+		if err != nil {
+			return err
+		}
+		budgetDefinitions = append(budgetDefinitions, budgets.FromAzure(azureBudgets)...)
+	}
+
+	summary, err := db.GenerateSummaryByResourceGroup(budgetMonths, nil)
+	if err != nil {
+		return err
+	}
+
+	utilisation := budgets.Evaluate(budgetDefinitions, summary, budgetThreshold)
+
+	if slices.ContainsFunc(utilisation, func(bu model.BudgetUtilisation) bool { return bu.Breached }) {
+		exitCode = 2
+	}
+
+	formatter, err := makeFormatter(formats.ResourceGroupGrouping, nil)
+	if err != nil {
+		return err
+	}
+
+	budgetFormatter, ok := formatter.(formats.BudgetFormatter)
+	if !ok {
+		return fmt.Errorf("the '%s' format does not support budget reports", format)
+	}
+
+	return writeOutput(func(w io.Writer) error {
+		return budgetFormatter.GenerateBudgets(w, utilisation)
+	})
+}
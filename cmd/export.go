@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"flag"
+	"github.com/dazfuller/azcosts/internal/sqlite"
+	"log"
+	"slices"
+	"strings"
+)
+
+func validateExportFlags(flags *flag.FlagSet) {
+	formatLower := strings.ToLower(exportFormat)
+	if !slices.Contains([]string{ParquetFormat, LongCsvFormat, NdjsonFormat}, formatLower) {
+		displayErrorMessage("a valid format must be specified", flags)
+	}
+
+	if !useStdOut && len(outputPath) == 0 {
+		displayErrorMessage("when not writing to stdout an output path must be specified", flags)
+	}
+}
+
+// exportCosts writes every collected cost in the database, rather than the costs of recent months as with generate.
+func exportCosts() error {
+	db, err := getCostManagementStore()
+	if err != nil {
+		return err
+	}
+	defer func(db *sqlite.CostManagementStore) {
+		err := db.Close()
+		if err != nil {
+			log.Printf("Unable to close data store: %e", err)
+		}
+	}(db)
+
+	return writeCostRecords(db, 0, exportFormat)
+}
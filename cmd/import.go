@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/exports"
+	"github.com/dazfuller/azcosts/internal/model"
+	"github.com/dazfuller/azcosts/internal/sqlite"
+	"log"
+	"slices"
+	"strings"
+)
+
+var (
+	costType         string
+	resourceGroupTag string
+)
+
+func validateImportFlags(flags *flag.FlagSet) {
+	if flags.NArg() == 0 {
+		displayErrorMessage("at least one export file or directory must be provided", flags)
+	}
+
+	costTypeLower := strings.ToLower(costType)
+	if costTypeLower != exports.BilledCost && costTypeLower != exports.EffectiveCost {
+		displayErrorMessage(fmt.Sprintf("the cost type must be either '%s' or '%s'", exports.BilledCost, exports.EffectiveCost), flags)
+	}
+}
+
+// importCostExports imports the costs from Cost Management export or FOCUS files, saving the costs of each subscription and
+// billing period using the same overwrite rules as when collecting from Azure. As the resource groups cannot be listed
+// offline, their status is recorded as unknown.
+func importCostExports(paths []string) error {
+	costs, err := exports.Load(paths, exports.Options{CostType: strings.ToLower(costType), ResourceGroupTag: resourceGroupTag})
+	if err != nil {
+		return err
+	}
+
+	db, err := getCostManagementStore()
+	if err != nil {
+		return err
+	}
+	defer func(db *sqlite.CostManagementStore) {
+		err := db.Close()
+		if err != nil {
+			log.Printf("Unable to close data store: %e", err)
+		}
+	}(db)
+
+	type subscriptionPeriod struct {
+		subscriptionId string
+		period         string
+	}
+
+	periodCosts := make(map[subscriptionPeriod][]model.ResourceGroupCost)
+	var keys []subscriptionPeriod
+	for _, cost := range costs {
+		key := subscriptionPeriod{cost.SubscriptionId, cost.BillingPeriod.Format("2006-01")}
+		if _, ok := periodCosts[key]; !ok {
+			keys = append(keys, key)
+		}
+		periodCosts[key] = append(periodCosts[key], cost)
+	}
+
+	for _, key := range keys {
+		existingPeriods, err := db.GetSubscriptionBillingPeriods(key.subscriptionId)
+		if err != nil {
+			return err
+		}
+
+		if !overwrite && slices.Contains(existingPeriods, key.period) {
+			log.Printf("Data for subscription %s for %s already exists, use the overwrite option to replace this data", key.subscriptionId, key.period)
+			continue
+		}
+
+		err = db.DeleteSubscriptionBillingPeriod(key.subscriptionId, key.period)
+		if err != nil {
+			return err
+		}
+
+		err = db.SaveCosts(periodCosts[key], nil)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Successfully imported billing data for subscription %s for %s", key.subscriptionId, key.period)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"github.com/dazfuller/azcosts/internal/formats"
+	"github.com/dazfuller/azcosts/internal/sqlite"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+var (
+	metricsAddress  string
+	metricsMonths   int
+	metricsTextfile string
+)
+
+func addMetricsFlags(flags *flag.FlagSet) {
+	flags.StringVar(&metricsAddress, "addr", "localhost:9464", "The address to listen on for scrape requests")
+	flags.IntVar(&metricsMonths, "months", 6, "The number of months of costs to expose")
+	flags.StringVar(&tenantId, "tenant", "", "A comma separated list of tenant ids to limit the metrics to")
+	flags.StringVar(&metricsTextfile, "textfile", "", "Writes the metrics to this file for the node_exporter textfile collector and exits, rather than serving them")
+}
+
+func validateMetricsFlags(flags *flag.FlagSet) {
+	if metricsMonths <= 0 {
+		displayErrorMessage("number of months must be greater than 0", flags)
+	}
+}
+
+// serveMetrics exposes the collected costs in the OpenMetrics format, either over HTTP at /metrics or by writing a
+// file for the node_exporter textfile collector. The costs are read from the store on each scrape so that newly
+// collected costs are exposed without restarting.
+func serveMetrics() error {
+	db, err := getCostManagementStore()
+	if err != nil {
+		return err
+	}
+	defer func(db *sqlite.CostManagementStore) {
+		err := db.Close()
+		if err != nil {
+			log.Printf("Unable to close data store: %e", err)
+		}
+	}(db)
+
+	if len(metricsTextfile) > 0 {
+		return writeMetricsTextfile(db, metricsTextfile)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler(db))
+
+	server := &http.Server{
+		Addr:              metricsAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Printf("Serving metrics at http://%s/metrics", metricsAddress)
+	return server.ListenAndServe()
+}
+
+// metricsHandler returns a handler which writes the metrics read from the store in response to each scrape request.
+func metricsHandler(db *sqlite.CostManagementStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var buffer bytes.Buffer
+		if err := writeMetrics(db, &buffer); err != nil {
+			log.Printf("Unable to read costs for scrape request: %s", err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", formats.OpenMetricsContentType)
+		_, _ = w.Write(buffer.Bytes())
+	}
+}
+
+// writeMetricsTextfile writes the metrics to a temporary file which is then renamed, so that the textfile collector
+// never reads a partially written file.
+func writeMetricsTextfile(db *sqlite.CostManagementStore, path string) error {
+	output, err := formats.CreateOutput(false, path)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	if err := writeMetrics(db, output); err != nil {
+		return err
+	}
+
+	return output.Commit()
+}
+
+// writeMetrics writes the costs in the store to w in the OpenMetrics format. A store without any costs for the
+// selected tenants is written as an exposition without any metrics, as it is not an error to be scraped before the
+// first collection.
+func writeMetrics(db *sqlite.CostManagementStore, w io.Writer) error {
+	writer := formats.NewOpenMetricsWriter(w)
+	err := db.StreamCosts(metricsMonths, tenantIds(), writer.Write)
+	if errors.Is(err, sqlite.ErrNoCostData) || errors.Is(err, sqlite.ErrNoTenantCostData) {
+		err = nil
+	}
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
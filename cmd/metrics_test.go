@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"github.com/dazfuller/azcosts/internal/formats"
+	"github.com/dazfuller/azcosts/internal/model"
+	"github.com/dazfuller/azcosts/internal/sqlite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestStore returns a store in a temporary directory containing the costs.
+func newTestStore(t *testing.T, costs ...model.ResourceGroupCost) *sqlite.CostManagementStore {
+	t.Helper()
+
+	db, err := sqlite.NewCostManagementStore(filepath.Join(t.TempDir(), "costs.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if len(costs) > 0 {
+		if err := db.SaveCosts(costs, []model.ResourceGroup{{Name: costs[0].Name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db
+}
+
+// setFlag sets a flag variable for the duration of the test.
+func setFlag[T any](t *testing.T, flag *T, value T) {
+	t.Helper()
+
+	previous := *flag
+	*flag = value
+	t.Cleanup(func() { *flag = previous })
+}
+
+func TestMetricsHandler(t *testing.T) {
+	now := time.Now().UTC()
+	cost := model.ResourceGroupCost{
+		SubscriptionId:   "00000000-0000-0000-0000-000000000001",
+		SubscriptionName: "Production",
+		TenantId:         "aaaaaaaa-0000-0000-0000-000000000000",
+		Name:             "rg-web",
+		BillingPeriod:    time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		Cost:             12.5,
+		CostUSD:          15,
+		Currency:         "GBP",
+	}
+
+	tests := []struct {
+		name   string
+		costs  []model.ResourceGroupCost
+		tenant string
+		want   []string
+	}{
+		{
+			name: "empty store",
+			want: []string{"# EOF\n"},
+		},
+		{
+			name:   "no costs for the tenant",
+			costs:  []model.ResourceGroupCost{cost},
+			tenant: "bbbbbbbb-0000-0000-0000-000000000000",
+			want:   []string{"# EOF\n"},
+		},
+		{
+			name:  "costs",
+			costs: []model.ResourceGroupCost{cost},
+			want: []string{
+				`azcosts_resource_group_cost{subscription="Production",subscription_id="00000000-0000-0000-0000-000000000001"`,
+				`azcosts_resource_group_cost_usd{subscription="Production",subscription_id="00000000-0000-0000-0000-000000000001"`,
+				"# EOF\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &metricsMonths, 6)
+			setFlag(t, &tenantId, tt.tenant)
+			db := newTestStore(t, tt.costs...)
+
+			response := httptest.NewRecorder()
+			metricsHandler(db)(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+			if response.Code != http.StatusOK {
+				t.Fatalf("metricsHandler() status = %d, want %d: %s", response.Code, http.StatusOK, response.Body.String())
+			}
+			if contentType := response.Header().Get("Content-Type"); contentType != formats.OpenMetricsContentType {
+				t.Errorf("metricsHandler() content type = %q, want %q", contentType, formats.OpenMetricsContentType)
+			}
+			body := response.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("metricsHandler() body = %q, want it to contain %q", body, want)
+				}
+			}
+			if !strings.HasSuffix(body, "# EOF\n") {
+				t.Errorf("metricsHandler() body = %q, want it to end with # EOF", body)
+			}
+		})
+	}
+}
+
+func TestWriteMetricsTextfileWithEmptyStore(t *testing.T) {
+	setFlag(t, &metricsMonths, 6)
+	setFlag(t, &tenantId, "")
+	db := newTestStore(t)
+
+	textfilePath := filepath.Join(t.TempDir(), "azcosts.prom")
+	if err := writeMetricsTextfile(db, textfilePath); err != nil {
+		t.Fatalf("writeMetricsTextfile() error = %v", err)
+	}
+
+	content, err := os.ReadFile(textfilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "# EOF\n" {
+		t.Errorf("writeMetricsTextfile() wrote %q, want %q", content, "# EOF\n")
+	}
+}
@@ -5,14 +5,18 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"github.com/dazfuller/azcosts/internal/analysis"
 	"github.com/dazfuller/azcosts/internal/azure"
+	"github.com/dazfuller/azcosts/internal/exports"
 	"github.com/dazfuller/azcosts/internal/formats"
 	"github.com/dazfuller/azcosts/internal/model"
 	"github.com/dazfuller/azcosts/internal/sqlite"
 	"github.com/google/uuid"
+	"io"
 	"log"
 	"os"
 	"path"
+	"path/filepath"
 	"slices"
 	"sort"
 	"strconv"
@@ -21,23 +25,67 @@ import (
 )
 
 const (
-	TextFormat  = "text"
-	CsvFormat   = "csv"
-	JsonFormat  = "json"
-	ExcelFormat = "excel"
+	TextFormat        = "text"
+	CsvFormat         = "csv"
+	JsonFormat        = "json"
+	ExcelFormat       = "excel"
+	FocusFormat       = "focus"
+	LongCsvFormat     = "csv-long"
+	NdjsonFormat      = "ndjson"
+	ParquetFormat     = "parquet"
+	HtmlFormat        = "html"
+	MarkdownFormat    = "markdown"
+	TemplateFormat    = "template"
+	OpenMetricsFormat = "openmetrics"
+)
+
+const (
+	ResourceGroupLevel   = "resource-group"
+	ManagementGroupLevel = "management-group"
+	TenantLevel          = "tenant"
+	SubscriptionLevel    = "subscription"
 )
 
 var (
-	subscriptionId   string
-	subscriptionName string
-	year             int
-	month            int
-	format           string
-	useStdOut        bool
-	outputPath       string
-	truncateDB       bool
-	overwrite        bool
-	generateMonths   int
+	subscriptionId    string
+	subscriptionName  string
+	year              int
+	month             int
+	format            string
+	useStdOut         bool
+	outputPath        string
+	truncateDB        bool
+	overwrite         bool
+	generateMonths    int
+	anomalyMonths     int
+	stdDevThreshold   float64
+	percentThreshold  float64
+	minimumCost       float64
+	exitCode          int
+	projectCurrent    bool
+	forecastMonths    int
+	budgetPath        string
+	budgetMonths      int
+	budgetThreshold   float64
+	collectBudgets    bool
+	useAzureBudgets   bool
+	collectForecast   int
+	useAzureForecast  bool
+	managementGroupId string
+	groupBy           string
+	billingAccountId  string
+	billingProfileId  string
+	statusGroupBy     string
+	collapsible       bool
+	exportFormat      string
+	templatePath      string
+	sortOrder         string
+	excelPivot        bool
+
+	// subscriptionTenants caches the tenant of each subscription listed during collection, and listedTenants the
+	// tenants for which the subscriptions have been listed.
+	subscriptionTenants = make(map[string]string)
+	listedTenants       []string
 )
 
 func Execute() {
@@ -51,8 +99,16 @@ func Execute() {
 	collectCmd := flag.NewFlagSet("collect", flag.ExitOnError)
 	generateCmd := flag.NewFlagSet("generate", flag.ExitOnError)
 	statusCmd := flag.NewFlagSet("status", flag.ExitOnError)
+	anomaliesCmd := flag.NewFlagSet("anomalies", flag.ExitOnError)
+	budgetCmd := flag.NewFlagSet("budget", flag.ExitOnError)
+	importCmd := flag.NewFlagSet("import", flag.ExitOnError)
+	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+	metricsCmd := flag.NewFlagSet("serve-metrics", flag.ExitOnError)
+	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	browseCmd := flag.NewFlagSet("browse", flag.ExitOnError)
 
 	subscriptionCmd.StringVar(&subscriptionName, "name", "", "Full or partial name to filter by, if not provided then a full list is returned")
+	addAuthFlags(subscriptionCmd)
 
 	subscriptionCmd.Usage = func() {
 		fmt.Println("Azure costs summary")
@@ -64,16 +120,23 @@ func Execute() {
 
 	collectCmd.StringVar(&subscriptionId, "subscription", "", "The id of the subscription to collect costs for")
 	collectCmd.StringVar(&subscriptionName, "name", "", "Full or partial name of the subscription if the id is not known")
+	collectCmd.StringVar(&managementGroupId, "management-group", "", "The id of a management group to collect costs for all of its subscriptions")
+	collectCmd.StringVar(&billingAccountId, "billing-account", "", "The id of a billing account, such as an EA enrollment, to collect costs for all of its subscriptions")
+	collectCmd.StringVar(&billingProfileId, "billing-profile", "", "The id of a billing profile within the billing account to collect costs for")
 	collectCmd.IntVar(&year, "year", time.Now().Year(), "The year of the billing period")
 	collectCmd.IntVar(&month, "month", int(time.Now().Month()), "The month of the billing period")
 	collectCmd.BoolVar(&truncateDB, "truncate", false, "If specified will truncate the existing data in the database")
 	collectCmd.BoolVar(&overwrite, "overwrite", false, "If specified then any existing data for a billing period will be overwritten with new data")
+	collectCmd.BoolVar(&collectBudgets, "budgets", false, "If specified then the subscription and resource group budgets defined in Azure are also collected")
+	collectCmd.IntVar(&collectForecast, "forecast", 0, "The number of months, starting with the current month, to collect the Cost Management forecast for")
+	addAuthFlags(collectCmd)
 
 	collectCmd.Usage = func() {
 		fmt.Println("Azure costs summary")
 		fmt.Println("Collects cost data from Microsoft Azure. The app makes use of")
 		fmt.Println("the DefaultAzureCredential (https://learn.microsoft.com/dotnet/api/azure.identity.defaultazurecredential)")
-		fmt.Println("type, and so running locally will use the Azure CLI tool for authentication if available.")
+		fmt.Println("type by default, and so running locally will use the Azure CLI tool for authentication if available.")
+		fmt.Println("A specific credential can be selected using the -auth argument.")
 		fmt.Println("The user must have billing reader permissions on the subscription.")
 		fmt.Println()
 		fmt.Println("Usage:")
@@ -81,10 +144,21 @@ func Execute() {
 	}
 
 	generateCmd.StringVar(&format, "format", "text", fmt.Sprintf(
-		"The output format to use. Allowed values are '%s', '%s', '%s', and '%s'", TextFormat, CsvFormat, JsonFormat, ExcelFormat))
+		"The output format to use. Allowed values are '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', and '%s'", TextFormat, CsvFormat, LongCsvFormat, JsonFormat, NdjsonFormat, ExcelFormat, HtmlFormat, MarkdownFormat, TemplateFormat, ParquetFormat, FocusFormat, OpenMetricsFormat))
 	generateCmd.BoolVar(&useStdOut, "stdout", false, "If set writes the data to stdout")
 	generateCmd.StringVar(&outputPath, "path", "", "The output path to write the summary data to when not writing to stdout")
 	generateCmd.IntVar(&generateMonths, "months", 6, "The number of months over which to report")
+	generateCmd.BoolVar(&projectCurrent, "project", false, "If set adds a projected end of month total for the current billing period")
+	generateCmd.IntVar(&forecastMonths, "forecast", 0, "The number of months following the last billing period to forecast")
+	generateCmd.StringVar(&groupBy, "by", ResourceGroupLevel, fmt.Sprintf(
+		"The level to summarise costs at. Allowed values are '%s', '%s', and '%s'", ResourceGroupLevel, ManagementGroupLevel, TenantLevel))
+	generateCmd.StringVar(&tenantId, "tenant", "", "A comma separated list of tenant ids to limit the report to")
+	generateCmd.BoolVar(&useAzureForecast, "azure-forecast", false, "If set includes the forecasts collected from Cost Management using 'collect -forecast'")
+	generateCmd.StringVar(&templatePath, "template", "", "The path to a Go template file used to render the template output")
+	generateCmd.BoolVar(&collapsible, "collapse", false, "If set the markdown output places the costs of each subscription in a collapsible section")
+	generateCmd.BoolVar(&excelPivot, "pivot", false, "If set the Excel output includes a sheet of every cost and a pivot table over it")
+	generateCmd.StringVar(&sortOrder, "sort", string(formats.SortByName), fmt.Sprintf(
+		"The order of the text output. Allowed values are '%s', '%s', and '%s'", formats.SortByName, formats.SortByTotal, formats.SortByLatest))
 
 	generateCmd.Usage = func() {
 		fmt.Println("Azure costs summary")
@@ -94,12 +168,116 @@ func Execute() {
 		generateCmd.PrintDefaults()
 	}
 
+	statusCmd.StringVar(&tenantId, "tenant", "", "A comma separated list of tenant ids to limit the output to")
+	statusCmd.StringVar(&statusGroupBy, "by", SubscriptionLevel, fmt.Sprintf(
+		"The level to group the output by. Allowed values are '%s' and '%s'", SubscriptionLevel, TenantLevel))
+
 	statusCmd.Usage = func() {
 		fmt.Println("Azure costs summary")
 		fmt.Println("Outputs information showing the collection status of subscriptions collected to date")
 		fmt.Println()
 		fmt.Println("Usage:")
-		subscriptionCmd.PrintDefaults()
+		statusCmd.PrintDefaults()
+	}
+
+	anomaliesCmd.StringVar(&format, "format", "text", fmt.Sprintf(
+		"The output format to use. Allowed values are '%s' and '%s'", TextFormat, JsonFormat))
+	anomaliesCmd.IntVar(&anomalyMonths, "months", 6, "The number of months of history, including the latest period, to compare against")
+	anomaliesCmd.Float64Var(&stdDevThreshold, "stddev", 2, "The number of standard deviations above the mean at which a cost is considered a spike, 0 to disable")
+	anomaliesCmd.Float64Var(&percentThreshold, "percent", 50, "The percentage increase over the mean at which a cost is considered an increase, 0 to disable")
+	anomaliesCmd.Float64Var(&minimumCost, "min-cost", 10, "The minimum cost in a period for a resource group to be considered significant")
+
+	anomaliesCmd.Usage = func() {
+		fmt.Println("Azure costs summary")
+		fmt.Println("Compares the latest collected billing period for each resource group against its history and")
+		fmt.Println("reports spikes, significant increases, new resource groups, and resource groups which have")
+		fmt.Println("stopped incurring costs. Exits with a status of 2 when anomalies are found.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		anomaliesCmd.PrintDefaults()
+	}
+
+	budgetCmd.StringVar(&budgetPath, "file", "", "The path to a YAML or CSV file containing the budget definitions")
+	budgetCmd.BoolVar(&useAzureBudgets, "azure", false, "If set includes the budgets collected from Azure using 'collect -budgets'")
+	budgetCmd.StringVar(&format, "format", "text", fmt.Sprintf(
+		"The output format to use. Allowed values are '%s', '%s', '%s', and '%s'", TextFormat, CsvFormat, JsonFormat, ExcelFormat))
+	budgetCmd.BoolVar(&useStdOut, "stdout", false, "If set writes the data to stdout")
+	budgetCmd.StringVar(&outputPath, "path", "", "The output path to write the budget report to when not writing to stdout")
+	budgetCmd.IntVar(&budgetMonths, "months", 1, "The number of months, including the latest period, to check budgets for")
+	budgetCmd.Float64Var(&budgetThreshold, "threshold", 100, "The default utilisation percentage at which a budget is breached")
+
+	budgetCmd.Usage = func() {
+		fmt.Println("Azure costs summary")
+		fmt.Println("Compares the collected costs against budgets defined in a YAML or CSV file and reports the")
+		fmt.Println("utilisation of each budget per billing period. Exits with a status of 2 when a budget threshold")
+		fmt.Println("is breached.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		budgetCmd.PrintDefaults()
+	}
+
+	importCmd.BoolVar(&truncateDB, "truncate", false, "If specified will truncate the existing data in the database")
+	importCmd.BoolVar(&overwrite, "overwrite", false, "If specified then any existing data for a billing period will be overwritten with the imported data")
+	importCmd.StringVar(&costType, "cost", exports.BilledCost, fmt.Sprintf(
+		"The FOCUS cost column to import. Allowed values are '%s' and '%s'", exports.BilledCost, exports.EffectiveCost))
+	importCmd.StringVar(&resourceGroupTag, "tag", "", "The name of a tag to use in place of the resource group when importing FOCUS files")
+
+	importCmd.Usage = func() {
+		fmt.Println("Azure costs summary")
+		fmt.Println("Imports the actual or amortized cost CSV files created by Cost Management exports, or FOCUS CSV")
+		fmt.Println("and Parquet files, rather than collecting the costs from the Cost Management API.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  azcosts import [flags] <file or directory>...")
+		importCmd.PrintDefaults()
+	}
+
+	exportCmd.StringVar(&exportFormat, "format", ParquetFormat, fmt.Sprintf(
+		"The output format to use. Allowed values are '%s', '%s', and '%s'", ParquetFormat, LongCsvFormat, NdjsonFormat))
+	exportCmd.BoolVar(&useStdOut, "stdout", false, "If set writes the data to stdout")
+	exportCmd.StringVar(&outputPath, "path", "", "The output path to write the data to when not writing to stdout")
+	exportCmd.StringVar(&tenantId, "tenant", "", "A comma separated list of tenant ids to limit the export to")
+
+	exportCmd.Usage = func() {
+		fmt.Println("Azure costs summary")
+		fmt.Println("Exports the cost of every resource group for every billing period collected to date, with one")
+		fmt.Println("record per resource group and billing period.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		exportCmd.PrintDefaults()
+	}
+
+	addMetricsFlags(metricsCmd)
+
+	metricsCmd.Usage = func() {
+		fmt.Println("Azure costs summary")
+		fmt.Println("Exposes the collected costs as OpenMetrics gauges for Prometheus to scrape, or writes them to a")
+		fmt.Println("file for the node_exporter textfile collector.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		metricsCmd.PrintDefaults()
+	}
+
+	addServeFlags(serveCmd)
+
+	serveCmd.Usage = func() {
+		fmt.Println("Azure costs summary")
+		fmt.Println("Serves a web dashboard and a read-only JSON API over the collected costs. The OpenAPI document")
+		fmt.Println("describing the API is available at /api/openapi.json.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		serveCmd.PrintDefaults()
+	}
+
+	addBrowseFlags(browseCmd)
+
+	browseCmd.Usage = func() {
+		fmt.Println("Azure costs summary")
+		fmt.Println("Browses the collected costs in an interactive terminal UI, with a sortable and filterable table")
+		fmt.Println("of costs and a roll-up by subscription.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		browseCmd.PrintDefaults()
 	}
 
 	if len(os.Args) < 2 || strings.Contains(strings.ToLower(os.Args[1]), "help") {
@@ -115,6 +293,7 @@ func Execute() {
 		if err != nil {
 			displayErrorMessage("", subscriptionCmd)
 		}
+		validateAuthFlags(subscriptionCmd)
 		err = displaySubscriptions()
 		break
 	case "collect":
@@ -123,6 +302,7 @@ func Execute() {
 			displayErrorMessage("", collectCmd)
 		}
 		validateCollectFlags(collectCmd)
+		validateAuthFlags(collectCmd)
 		err = collectBillingData()
 		break
 	case "generate":
@@ -134,10 +314,71 @@ func Execute() {
 		err = generateBillingSummary()
 		break
 	case "status":
+		err = statusCmd.Parse(os.Args[2:])
+		if err != nil {
+			displayErrorMessage("", statusCmd)
+		}
+		validateStatusFlags(statusCmd)
 		err = displayCollectionStatus()
 		break
+	case "anomalies":
+		err = anomaliesCmd.Parse(os.Args[2:])
+		if err != nil {
+			displayErrorMessage("", anomaliesCmd)
+		}
+		validateAnomaliesFlags(anomaliesCmd)
+		err = displayAnomalies()
+		break
+	case "budget":
+		err = budgetCmd.Parse(os.Args[2:])
+		if err != nil {
+			displayErrorMessage("", budgetCmd)
+		}
+		validateBudgetFlags(budgetCmd)
+		err = generateBudgetReport()
+		break
+	case "import":
+		err = importCmd.Parse(os.Args[2:])
+		if err != nil {
+			displayErrorMessage("", importCmd)
+		}
+		validateImportFlags(importCmd)
+		err = importCostExports(importCmd.Args())
+		break
+	case "export":
+		err = exportCmd.Parse(os.Args[2:])
+		if err != nil {
+			displayErrorMessage("", exportCmd)
+		}
+		validateExportFlags(exportCmd)
+		err = exportCosts()
+		break
+	case "serve-metrics":
+		err = metricsCmd.Parse(os.Args[2:])
+		if err != nil {
+			displayErrorMessage("", metricsCmd)
+		}
+		validateMetricsFlags(metricsCmd)
+		err = serveMetrics()
+		break
+	case "serve":
+		err = serveCmd.Parse(os.Args[2:])
+		if err != nil {
+			displayErrorMessage("", serveCmd)
+		}
+		validateServeFlags(serveCmd)
+		err = serveApi()
+		break
+	case "browse":
+		err = browseCmd.Parse(os.Args[2:])
+		if err != nil {
+			displayErrorMessage("", browseCmd)
+		}
+		validateBrowseFlags(browseCmd)
+		err = browseCosts()
+		break
 	default:
-		fmt.Println("Unexpected command, expected 'subscription', 'collect', 'generate', or 'status'")
+		fmt.Println("Unexpected command, expected 'subscription', 'collect', 'generate', 'status', 'anomalies', 'budget', 'import', 'export', 'serve-metrics', 'serve', or 'browse'")
 		fmt.Println()
 		displayTopLevelUsage()
 		os.Exit(1)
@@ -146,11 +387,32 @@ func Execute() {
 	if err != nil {
 		panic(err)
 	}
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
 }
 
 func validateCollectFlags(flags *flag.FlagSet) {
-	if len(subscriptionId) == 0 && len(subscriptionName) == 0 {
-		displayErrorMessage("either a subscription id or name must be provided", flags)
+	scopeCount := 0
+	for _, scope := range []string{subscriptionId + subscriptionName, managementGroupId, billingAccountId} {
+		if len(scope) > 0 {
+			scopeCount++
+		}
+	}
+
+	tenants := tenantIds()
+
+	if scopeCount == 0 && len(tenants) == 0 {
+		displayErrorMessage("either a subscription id, name, management group, billing account, or tenant must be provided", flags)
+	} else if scopeCount > 1 {
+		displayErrorMessage("only one of a subscription, management group, or billing account can be provided", flags)
+	} else if scopeCount > 0 && len(tenants) > 1 {
+		displayErrorMessage("when collecting from more than one tenant all subscriptions in each tenant are collected, and so a subscription, management group, or billing account cannot be provided", flags)
+	}
+
+	if len(billingProfileId) > 0 && len(billingAccountId) == 0 {
+		displayErrorMessage("a billing profile can only be used with a billing account", flags)
 	}
 
 	if len(subscriptionId) > 0 {
@@ -168,6 +430,10 @@ func validateCollectFlags(flags *flag.FlagSet) {
 	if billingDate.After(time.Now().UTC()) {
 		displayErrorMessage("invalid billing period, must be in the past", flags)
 	}
+
+	if collectForecast < 0 || collectForecast > 12 {
+		displayErrorMessage("number of forecast months must be between 0 and 12", flags)
+	}
 }
 
 func validateGenerateFlags(flags *flag.FlagSet) {
@@ -176,6 +442,14 @@ func validateGenerateFlags(flags *flag.FlagSet) {
 		CsvFormat,
 		JsonFormat,
 		ExcelFormat,
+		FocusFormat,
+		LongCsvFormat,
+		NdjsonFormat,
+		ParquetFormat,
+		HtmlFormat,
+		MarkdownFormat,
+		TemplateFormat,
+		OpenMetricsFormat,
 	}
 
 	formatLower := strings.ToLower(format)
@@ -185,13 +459,68 @@ func validateGenerateFlags(flags *flag.FlagSet) {
 
 	if !useStdOut && len(outputPath) == 0 {
 		displayErrorMessage("when not writing to stdout an output path must be specified", flags)
-	} else if formatLower == ExcelFormat && len(outputPath) == 0 {
-		displayErrorMessage("excel output cannot be written to stdout and so an output path must be specified", flags)
 	}
 
 	if generateMonths <= 0 {
 		displayErrorMessage("number of months must be greater than 0", flags)
 	}
+
+	if forecastMonths < 0 {
+		displayErrorMessage("number of forecast months cannot be negative", flags)
+	}
+
+	groupByLower := strings.ToLower(groupBy)
+	if !slices.Contains([]string{ResourceGroupLevel, ManagementGroupLevel, TenantLevel}, groupByLower) {
+		displayErrorMessage("a valid level to summarise by must be specified", flags)
+	} else if groupByLower == ManagementGroupLevel && useAzureForecast {
+		displayErrorMessage("azure forecasts cannot be included when summarising by management group", flags)
+	} else if groupByLower != ResourceGroupLevel && formatLower == FocusFormat {
+		displayErrorMessage("focus output can only be generated when summarising by resource group", flags)
+	} else if groupByLower != ResourceGroupLevel && isLongFormat(formatLower) {
+		displayErrorMessage("long-form output can only be generated when summarising by resource group", flags)
+	}
+
+	if formatLower == FocusFormat && (projectCurrent || forecastMonths > 0 || useAzureForecast) {
+		displayErrorMessage("projections and forecasts cannot be included in focus output", flags)
+	}
+
+	if formatLower == TemplateFormat && len(templatePath) == 0 {
+		displayErrorMessage("a template file must be specified when using the template format", flags)
+	} else if formatLower != TemplateFormat && len(templatePath) > 0 {
+		displayErrorMessage("a template file can only be used with the template format", flags)
+	}
+
+	if collapsible && formatLower != MarkdownFormat {
+		displayErrorMessage("collapsible sections can only be used with markdown output", flags)
+	}
+
+	if excelPivot && formatLower != ExcelFormat {
+		displayErrorMessage("a pivot table can only be added to excel output", flags)
+	}
+
+	sortOrder = strings.ToLower(sortOrder)
+	if !slices.Contains([]formats.SortOrder{formats.SortByName, formats.SortByTotal, formats.SortByLatest}, formats.SortOrder(sortOrder)) {
+		displayErrorMessage("a valid sort order must be specified", flags)
+	} else if formats.SortOrder(sortOrder) != formats.SortByName && formatLower != TextFormat {
+		displayErrorMessage("a sort order can only be used with text output", flags)
+	}
+
+	if isLongFormat(formatLower) && (projectCurrent || forecastMonths > 0 || useAzureForecast) {
+		displayErrorMessage("forecasts cannot be included in long-form output", flags)
+	}
+}
+
+// isLongFormat returns true if the format writes a record per resource group and billing period, rather than a
+// summary with a column per billing period.
+func isLongFormat(format string) bool {
+	return format == LongCsvFormat || format == NdjsonFormat || format == ParquetFormat || format == OpenMetricsFormat
+}
+
+func validateStatusFlags(flags *flag.FlagSet) {
+	groupByLower := strings.ToLower(statusGroupBy)
+	if groupByLower != SubscriptionLevel && groupByLower != TenantLevel {
+		displayErrorMessage("a valid level to group by must be specified", flags)
+	}
 }
 
 func displaySubscriptions() error {
@@ -212,16 +541,31 @@ func displaySubscriptions() error {
 
 	var subscriptions []model.Subscription
 
-	if len(subscriptionName) > 0 {
-		subscriptions, err = svc.FindSubscription(subscriptionName)
-		if err != nil {
-			return err
+	tenants := tenantIds()
+	if len(tenants) == 0 {
+		tenants = []string{""}
+	}
+
+	for i, tenant := range tenants {
+		if i > 0 {
+			err = useTenant(tenant)
+			if err != nil {
+				return err
+			}
+			svc = azure.NewSubscriptionService()
+		}
+
+		var tenantSubscriptions []model.Subscription
+		if len(subscriptionName) > 0 {
+			tenantSubscriptions, err = svc.FindSubscription(subscriptionName)
+		} else {
+			tenantSubscriptions, err = svc.GetSubscriptions()
 		}
-	} else {
-		subscriptions, err = svc.GetSubscriptions()
 		if err != nil {
 			return err
 		}
+
+		subscriptions = append(subscriptions, tenantSubscriptions...)
 	}
 
 	sort.Slice(subscriptions, func(a, b int) bool {
@@ -262,17 +606,97 @@ func collectBillingData() error {
 		}
 	}(db)
 
-	if len(subscriptionId) == 0 {
-		subscriptionId, err = getSubscriptionId()
+	billingDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+
+	tenants := tenantIds()
+	if len(tenants) <= 1 {
+		return collectTenantBillingData(db, strings.Join(tenants, ""), billingDate)
+	}
+
+	for i, tenant := range tenants {
+		// The credential for the first tenant is created when validating the auth flags
+		if i > 0 {
+			err = useTenant(tenant)
+			if err != nil {
+				return err
+			}
+		}
+
+		log.Printf("Collecting billing data for tenant %s", tenant)
+
+		err = collectTenantBillingData(db, tenant, billingDate)
 		if err != nil {
 			return err
 		}
 	}
 
-	billingDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return nil
+}
+
+// collectTenantBillingData collects the billing data for the selected scope using the current credential. Where no
+// subscription, management group, or billing account has been selected then every subscription in the tenant is
+// collected.
+func collectTenantBillingData(db *sqlite.CostManagementStore, tenant string, billingDate time.Time) error {
+	var subscriptionIds []string
+	var err error
+
+	if len(managementGroupId) > 0 {
+		subscriptionIds, err = processManagementGroupBillingPeriods(db, tenant, billingDate)
+		if err != nil {
+			return err
+		}
+	} else if len(billingAccountId) > 0 {
+		scope := azure.BillingAccountScope(billingAccountId)
+		if len(billingProfileId) > 0 {
+			scope = azure.BillingProfileScope(billingAccountId, billingProfileId)
+		}
+
+		subscriptionIds, err = processScopeBillingPeriods(db, tenant, scope, billingDate, true)
+		if err != nil {
+			return err
+		}
+	} else if len(subscriptionId) == 0 && len(subscriptionName) == 0 {
+		subscriptionIds, err = processTenantBillingPeriods(db, tenant, billingDate)
+		if err != nil {
+			return err
+		}
+	} else {
+		if len(subscriptionId) == 0 {
+			subscriptionId, err = getSubscriptionId()
+			if err != nil {
+				return err
+			}
+		}
+
+		err = processSubscriptionBillingPeriods(db, tenant, subscriptionId, billingDate)
+		if err != nil {
+			return err
+		}
+
+		subscriptionIds = []string{subscriptionId}
+	}
+
+	// Budgets are collected whether or not the costs for the billing period were already collected, as they are not
+	// specific to a billing period
+	if collectBudgets {
+		for _, id := range subscriptionIds {
+			err = processSubscriptionBudgets(db, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if collectForecast > 0 {
+		for _, id := range subscriptionIds {
+			err = processSubscriptionForecast(db, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-	err = processSubscriptionBillingPeriods(db, billingDate)
-	return err
+	return nil
 }
 
 func getSubscriptionId() (string, error) {
@@ -328,33 +752,181 @@ func generateBillingSummary() error {
 		}
 	}(db)
 
-	summary, err := db.GenerateSummaryByResourceGroup(generateMonths)
+	if isLongFormat(strings.ToLower(format)) {
+		return writeCostRecords(db, generateMonths, format)
+	}
+
+	summary, grouping, err := summarise(db, summaryOptions{
+		level:         groupBy,
+		months:        generateMonths,
+		tenants:       tenantIds(),
+		project:       projectCurrent,
+		forecast:      forecastMonths,
+		azureForecast: useAzureForecast,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The pivot table is built from the cost of each resource group, in the same way as the long-form outputs, rather
+	// than from the summary
+	var data []model.CostRecord
+	if excelPivot {
+		err = db.StreamCosts(generateMonths, tenantIds(), func(record model.CostRecord) error {
+			data = append(data, record)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	formatter, err := makeFormatter(grouping, data)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(func(w io.Writer) error {
+		return formatter.Generate(w, summary)
+	})
+}
+
+// summaryOptions determines how the costs are summarised, using the same values as the arguments of generate.
+type summaryOptions struct {
+	level         string
+	months        int
+	tenants       []string
+	project       bool
+	forecast      int
+	azureForecast bool
+}
+
+// summarise summarises the costs at the level given in the options, adding any projections and forecasts, and
+// returns the summary with the grouping used to label it.
+func summarise(db *sqlite.CostManagementStore, options summaryOptions) ([]model.ResourceGroupSummary, formats.Grouping, error) {
+	var summary []model.ResourceGroupSummary
+	var err error
+	grouping := formats.ResourceGroupGrouping
+
+	switch strings.ToLower(options.level) {
+	case ManagementGroupLevel:
+		summary, err = db.GenerateSummaryByManagementGroup(options.months, options.tenants)
+		grouping = formats.ManagementGroupGrouping
+	case TenantLevel:
+		summary, err = db.GenerateSummaryByTenant(options.months, options.tenants)
+		grouping = formats.TenantGrouping
+	default:
+		summary, err = db.GenerateSummaryByResourceGroup(options.months, options.tenants)
+	}
+	if err != nil {
+		return nil, grouping, err
+	}
+
+	now := time.Now().UTC()
+
+	if options.project {
+		collectionTimes, err := db.GetCollectionTimes(now.Format("2006-01"))
+		if err != nil {
+			return nil, grouping, err
+		}
+		summary = analysis.ProjectCurrentPeriod(summary, collectionTimes, now)
+	}
+
+	if options.forecast > 0 {
+		summary = analysis.ForecastPeriods(summary, options.forecast, now)
+	}
+
+	if options.azureForecast {
+		forecasts, err := db.ListForecasts(now.Format("2006-01"))
+		if err != nil {
+			return nil, grouping, err
+		}
+		summary = analysis.AllocateForecasts(summary, forecasts, now)
+	}
+
+	return summary, grouping, nil
+}
+
+// writeCostRecords writes the costs over the given number of months in a long-form format, streaming each record from
+// the store to the output. When months is 0 every collected billing period is written.
+func writeCostRecords(db *sqlite.CostManagementStore, months int, format string) error {
+	return writeOutput(func(w io.Writer) error {
+		writer, err := makeRecordWriter(format, w)
+		if err != nil {
+			return err
+		}
+
+		err = db.StreamCosts(months, tenantIds(), writer.Write)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+
+		return err
+	})
+}
+
+// writeOutput calls write with stdout, or with a temporary file which replaces the file at the output path only if
+// write succeeds, so that a failed report does not destroy the previous one.
+func writeOutput(write func(w io.Writer) error) error {
+	output, err := formats.CreateOutput(useStdOut, outputPath)
 	if err != nil {
 		return err
 	}
+	defer func(output *formats.Output) {
+		err := output.Close()
+		if err != nil {
+			log.Printf("Unable to remove temporary output file: %s", err.Error())
+		}
+	}(output)
 
-	var formatter formats.Formatter
+	if err = write(output); err != nil {
+		return err
+	}
+
+	return output.Commit()
+}
+
+func makeRecordWriter(format string, w io.Writer) (formats.RecordWriter, error) {
+	switch strings.ToLower(format) {
+	case LongCsvFormat:
+		return formats.NewLongCsvWriter(w), nil
+	case NdjsonFormat:
+		return formats.NewNdjsonWriter(w), nil
+	case ParquetFormat:
+		return formats.NewParquetWriter(w), nil
+	case OpenMetricsFormat:
+		return formats.NewOpenMetricsWriter(w), nil
+	}
 
+	return nil, fmt.Errorf("unsupported format '%s'", format)
+}
+
+func makeFormatter(grouping formats.Grouping, data []model.CostRecord) (formats.Formatter, error) {
 	switch strings.ToLower(format) {
 	case TextFormat:
-		formatter, err = formats.MakeTextFormatter(useStdOut, outputPath)
-		break
+		return formats.MakeTextFormatter(grouping, formats.SortOrder(sortOrder)), nil
 	case CsvFormat:
-		formatter, err = formats.MakeCsvFormatter(useStdOut, outputPath)
-		break
+		return formats.MakeCsvFormatter(grouping), nil
 	case JsonFormat:
-		formatter, err = formats.MakeJsonFormatter(useStdOut, outputPath)
-		break
+		return formats.MakeJsonFormatter(grouping), nil
 	case ExcelFormat:
-		formatter, err = formats.MakeExcelFormatter(outputPath)
-		break
-	}
-	if err != nil {
-		return err
+		return formats.MakeExcelFormatter(grouping, data), nil
+	case HtmlFormat:
+		return formats.MakeHtmlFormatter(grouping), nil
+	case MarkdownFormat:
+		return formats.MakeMarkdownFormatter(grouping, collapsible), nil
+	case TemplateFormat:
+		return formats.MakeTemplateFormatter(grouping, templatePath)
+	case FocusFormat:
+		return formats.MakeFocusFormatter(!useStdOut && strings.EqualFold(filepath.Ext(outputPath), ".parquet")), nil
 	}
 
-	err = formatter.Generate(summary)
-	return err
+	return nil, fmt.Errorf("unsupported format '%s'", format)
+}
+
+func printCollectionStatusHeader() {
+	fmt.Printf("%-51s%-38s%-9s\n", "Subscription", "Subscription Id", "Period")
+	fmt.Printf("%-51s%-38s%-9s\n", strings.Repeat("=", 50), strings.Repeat("=", 37), strings.Repeat("=", 8))
 }
 
 func displayCollectionStatus() error {
@@ -374,10 +946,26 @@ func displayCollectionStatus() error {
 		return err
 	}
 
-	fmt.Printf("%-51s%-38s%-9s\n", "Subscription", "Subscription Id", "Period")
-	fmt.Printf("%-51s%-38s%-9s\n", strings.Repeat("=", 50), strings.Repeat("=", 37), strings.Repeat("=", 8))
+	summaries = filterCollectionSummaries(summaries, tenantIds())
+
+	byTenant := strings.ToLower(statusGroupBy) == TenantLevel
+	if !byTenant {
+		printCollectionStatusHeader()
+	}
+
+	for i, summary := range summaries {
+		if byTenant && (i == 0 || summary.TenantId != summaries[i-1].TenantId) {
+			tenant := summary.TenantId
+			if len(tenant) == 0 {
+				tenant = "(Unknown)"
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("Tenant: %s\n\n", tenant)
+			printCollectionStatusHeader()
+		}
 
-	for _, summary := range summaries {
 		name := summary.SubscriptionName
 		if len(name) > 50 {
 			name = name[:50]
@@ -389,6 +977,20 @@ func displayCollectionStatus() error {
 	return nil
 }
 
+// filterCollectionSummaries removes the summaries of subscriptions which are not in one of the tenants. When no
+// tenants are provided the summaries are returned unchanged.
+func filterCollectionSummaries(summaries []model.CollectionSummary, tenants []string) []model.CollectionSummary {
+	if len(tenants) == 0 {
+		return summaries
+	}
+
+	return slices.DeleteFunc(summaries, func(s model.CollectionSummary) bool {
+		return !slices.ContainsFunc(tenants, func(t string) bool {
+			return strings.EqualFold(t, s.TenantId)
+		})
+	})
+}
+
 func displayTopLevelUsage() {
 	fmt.Println(`Azure costs summary
 A tool for collecting billing data from Azure, and producing summarized outputs
@@ -404,6 +1006,13 @@ Available Commands:
     collect          Collects data from Azure and persists into a local store
     generate         Produces a summarized output of the billing data in multiple formats
     status           Displays the billing periods collected for each subscription
+    anomalies        Reports resource groups whose latest costs depart from their history
+    budget           Reports the utilisation of budgets against the collected costs
+    import           Imports the costs from Cost Management export files
+    export           Exports every collected cost in a long-form format
+    serve-metrics    Exposes the collected costs as metrics for Prometheus
+    serve            Serves a web dashboard and JSON API over the collected costs
+    browse           Browses the collected costs in an interactive terminal UI
 
 Flags:
     -h, -help        Help for azcosts`)
@@ -417,9 +1026,8 @@ func displayErrorMessage(msg string, flags *flag.FlagSet) {
 	os.Exit(1)
 }
 
-func processSubscriptionBillingPeriods(db *sqlite.CostManagementStore, billingDate time.Time) error {
+func processSubscriptionBillingPeriods(db *sqlite.CostManagementStore, tenant string, subscriptionId string, billingDate time.Time) error {
 	svc := azure.NewCostService()
-	rgSvc := azure.NewResourceGroupService()
 	existingPeriods, err := db.GetSubscriptionBillingPeriods(subscriptionId)
 	if err != nil {
 		return err
@@ -432,14 +1040,128 @@ func processSubscriptionBillingPeriods(db *sqlite.CostManagementStore, billingDa
 		return nil
 	}
 
-	rgs, err := rgSvc.ListResourceGroups(subscriptionId)
+	costs, err := svc.ResourceGroupCostsForPeriod(subscriptionId, billingDate.Year(), int(billingDate.Month()))
 	if err != nil {
 		return err
 	}
 
-	costs, err := svc.ResourceGroupCostsForPeriod(subscriptionId, billingDate.Year(), int(billingDate.Month()))
+	return saveSubscriptionCosts(db, tenant, subscriptionId, period, costs, false)
+}
+
+// processTenantBillingPeriods collects the costs of every subscription available to the current credential in the
+// tenant, returning the ids of the subscriptions collected.
+func processTenantBillingPeriods(db *sqlite.CostManagementStore, tenant string, billingDate time.Time) ([]string, error) {
+	svc := azure.NewSubscriptionService()
+	subscriptions, err := svc.GetSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptionIds := make([]string, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		subscriptionTenants[sub.Id] = sub.TenantId
+
+		err = processSubscriptionBillingPeriods(db, tenant, sub.Id, billingDate)
+		if err != nil {
+			return nil, err
+		}
+
+		subscriptionIds = append(subscriptionIds, sub.Id)
+	}
+
+	return subscriptionIds, nil
+}
+
+// processManagementGroupBillingPeriods collects the costs of every subscription under the management group, storing
+// the management group hierarchy alongside them. The ids of the subscriptions with costs in the billing period are
+// returned.
+func processManagementGroupBillingPeriods(db *sqlite.CostManagementStore, tenant string, billingDate time.Time) ([]string, error) {
+	mgSvc := azure.NewManagementGroupService()
+
+	hierarchy, err := mgSvc.GetHierarchy(managementGroupId)
 	if err != nil {
+		return nil, err
+	}
+
+	err = db.SaveManagementGroupHierarchy(hierarchy)
+	if err != nil {
+		return nil, err
+	}
+
+	return processScopeBillingPeriods(db, tenant, azure.ManagementGroupScope(managementGroupId), billingDate, false)
+}
+
+// processScopeBillingPeriods collects the costs of every subscription within a scope, such as a management group or
+// billing account, with a single query. The costs of each subscription are then saved using the same overwrite rules
+// as when collecting a single subscription. The ids of the subscriptions with costs in the billing period are returned.
+//
+// Billing scopes can include subscriptions which the current credential cannot read, so when unlistedGroups is set a
+// subscription whose resource groups cannot be listed is still saved, with the status of its resource groups unknown.
+func processScopeBillingPeriods(db *sqlite.CostManagementStore, tenant string, scope string, billingDate time.Time, unlistedGroups bool) ([]string, error) {
+	svc := azure.NewCostService()
+
+	costs, err := svc.ScopeCostsForPeriod(scope, billingDate.Year(), int(billingDate.Month()))
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptionCosts := make(map[string][]model.ResourceGroupCost)
+	var subscriptionIds []string
+	unassigned := 0
+	for _, cost := range costs {
+		if len(cost.SubscriptionId) == 0 {
+			unassigned++
+			continue
+		}
+		if _, ok := subscriptionCosts[cost.SubscriptionId]; !ok {
+			subscriptionIds = append(subscriptionIds, cost.SubscriptionId)
+		}
+		subscriptionCosts[cost.SubscriptionId] = append(subscriptionCosts[cost.SubscriptionId], cost)
+	}
+	slices.Sort(subscriptionIds)
+
+	if unassigned > 0 {
+		log.Printf("Skipped %d cost entries which are not associated with a subscription", unassigned)
+	}
+
+	period := billingDate.Format("2006-01")
+
+	for _, id := range subscriptionIds {
+		existingPeriods, err := db.GetSubscriptionBillingPeriods(id)
+		if err != nil {
+			return nil, err
+		}
+
+		if !overwrite && slices.Contains(existingPeriods, period) {
+			log.Printf("Data for subscription %s for the selected billing period already exists, use the overwrite option to replace this data", id)
+			continue
+		}
+
+		err = saveSubscriptionCosts(db, tenant, id, period, subscriptionCosts[id], unlistedGroups)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return subscriptionIds, nil
+}
+
+// saveSubscriptionCosts replaces the costs of the subscription for the billing period. Each cost is recorded against
+// the tenant of the subscription. If the resource groups of the subscription cannot be listed then an error is returned,
+// unless unlistedGroups is set, in which case the costs are saved with the status of the resource groups unknown.
+func saveSubscriptionCosts(db *sqlite.CostManagementStore, tenant string, subscriptionId string, period string, costs []model.ResourceGroupCost, unlistedGroups bool) error {
+	subscriptionTenant := tenantForSubscription(tenant, subscriptionId)
+	for i := range costs {
+		costs[i].TenantId = subscriptionTenant
+	}
+
+	rgSvc := azure.NewResourceGroupService()
+	rgs, err := rgSvc.ListResourceGroups(subscriptionId)
+	if err != nil && !unlistedGroups {
 		return err
+	} else if err != nil {
+		log.Printf("Unable to list the resource groups for subscription %s, their status will be recorded as unknown: %s", subscriptionId, err.Error())
+		rgs = nil
 	}
 
 	err = db.DeleteSubscriptionBillingPeriod(subscriptionId, period)
@@ -457,6 +1179,78 @@ func processSubscriptionBillingPeriods(db *sqlite.CostManagementStore, billingDa
 	return nil
 }
 
+// tenantForSubscription returns the id of the tenant the subscription belongs to, as reported by the subscriptions
+// available to the current credential. Where the subscription is not available, such as when it is collected through
+// a billing account, the tenant being collected is returned instead.
+func tenantForSubscription(tenant string, subscriptionId string) string {
+	if id, ok := subscriptionTenants[subscriptionId]; ok && len(id) > 0 {
+		return id
+	}
+
+	if !slices.Contains(listedTenants, tenant) {
+		listedTenants = append(listedTenants, tenant)
+
+		svc := azure.NewSubscriptionService()
+		subscriptions, err := svc.GetSubscriptions()
+		if err != nil {
+			log.Printf("Unable to list the subscriptions to determine their tenant: %s", err.Error())
+		}
+
+		for _, sub := range subscriptions {
+			subscriptionTenants[sub.Id] = sub.TenantId
+		}
+
+		if id, ok := subscriptionTenants[subscriptionId]; ok && len(id) > 0 {
+			return id
+		}
+	}
+
+	return tenant
+}
+
+// processSubscriptionBudgets replaces the budgets collected for the subscription with those currently defined for the
+// subscription and each of its resource groups.
+func processSubscriptionBudgets(db *sqlite.CostManagementStore, subscriptionId string) error {
+	rgSvc := azure.NewResourceGroupService()
+	rgs, err := rgSvc.ListResourceGroups(subscriptionId)
+	if err != nil {
+		return fmt.Errorf("unable to list the resource groups of subscription %s to collect their budgets: %s", subscriptionId, err.Error())
+	}
+
+	budgetSvc := azure.NewBudgetService()
+	budgets, err := budgetSvc.ListSubscriptionBudgets(subscriptionId, rgs)
+	if err != nil {
+		return err
+	}
+
+	err = db.SaveBudgets(subscriptionId, budgets)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Successfully collected and saved %d budget(s) for subscription %s", len(budgets), subscriptionId)
+
+	return nil
+}
+
+func processSubscriptionForecast(db *sqlite.CostManagementStore, subscriptionId string) error {
+	svc := azure.NewCostService()
+
+	forecasts, err := svc.SubscriptionForecast(subscriptionId, collectForecast)
+	if err != nil {
+		return err
+	}
+
+	err = db.SaveForecasts(subscriptionId, forecasts)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Successfully collected and saved the forecast for subscription %s for %d month(s)", subscriptionId, len(forecasts))
+
+	return nil
+}
+
 func getCostManagementStore() (*sqlite.CostManagementStore, error) {
 	dbPath, err := getDatabasePath()
 	if err != nil {
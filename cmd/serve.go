@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/formats"
+	"github.com/dazfuller/azcosts/internal/model"
+	"github.com/dazfuller/azcosts/internal/sqlite"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed openapi.json
+var openApiDocument []byte
+
+// webContent is the dashboard served alongside the API. It is embedded in the binary, and does not reference any
+// external resources, so that it works without network access.
+//
+//go:embed web
+var webContent embed.FS
+
+var (
+	serveAddress string
+	apiToken     string
+	serveUi      bool
+)
+
+func addServeFlags(flags *flag.FlagSet) {
+	flags.StringVar(&serveAddress, "addr", "localhost:8080", "The address to listen on")
+	flags.StringVar(&apiToken, "token", "", "A bearer token which clients must provide, if not set then AZCOSTS_API_TOKEN is used if available")
+	flags.BoolVar(&serveUi, "ui", true, "If set serves the web dashboard at the root of the server")
+}
+
+func validateServeFlags(flags *flag.FlagSet) {
+	if len(apiToken) == 0 {
+		apiToken = os.Getenv("AZCOSTS_API_TOKEN")
+	}
+
+	if len(serveAddress) == 0 {
+		displayErrorMessage("an address to listen on must be specified", flags)
+	}
+}
+
+// serveApi serves a read-only JSON API over the cost store, so that other tools can read the collected costs without
+// running the CLI.
+func serveApi() error {
+	db, err := getCostManagementStore()
+	if err != nil {
+		return err
+	}
+	defer func(db *sqlite.CostManagementStore) {
+		err := db.Close()
+		if err != nil {
+			log.Printf("Unable to close data store: %e", err)
+		}
+	}(db)
+
+	server := &http.Server{
+		Addr:              serveAddress,
+		Handler:           apiHandler(db),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if len(apiToken) == 0 {
+		log.Printf("No token has been provided, the API does not require authentication")
+	}
+
+	if serveUi {
+		log.Printf("Serving the dashboard at http://%s/", serveAddress)
+	}
+	log.Printf("Serving the API at http://%s/api", serveAddress)
+	return server.ListenAndServe()
+}
+
+func apiHandler(db *sqlite.CostManagementStore) http.Handler {
+	api := http.NewServeMux()
+
+	api.HandleFunc("GET /api/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		subscriptions, err := db.ListCollectedSubscriptions()
+		if err != nil {
+			writeApiError(w, err)
+			return
+		}
+
+		if tenants := parseTenantIds(r.URL.Query().Get("tenant")); len(tenants) > 0 {
+			subscriptions = slices.DeleteFunc(subscriptions, func(s model.Subscription) bool {
+				return !slices.ContainsFunc(tenants, func(t string) bool {
+					return strings.EqualFold(t, s.TenantId)
+				})
+			})
+		}
+
+		writeApiResponse(w, subscriptions)
+	})
+
+	api.HandleFunc("GET /api/billing-periods", func(w http.ResponseWriter, r *http.Request) {
+		months, err := queryInt(r, "months", 6)
+		if err == nil && months <= 0 {
+			err = fmt.Errorf("months must be greater than 0")
+		}
+		if err != nil {
+			writeApiError(w, badRequest{err})
+			return
+		}
+
+		var billingPeriods []string
+		if subscription := r.URL.Query().Get("subscription"); len(subscription) > 0 {
+			billingPeriods, err = db.GetSubscriptionBillingPeriods(subscription)
+		} else {
+			billingPeriods, err = db.GetAllBillingPeriods(months)
+		}
+		if err != nil {
+			writeApiError(w, err)
+			return
+		}
+
+		writeApiResponse(w, billingPeriods)
+	})
+
+	api.HandleFunc("GET /api/status", func(w http.ResponseWriter, r *http.Request) {
+		summaries, err := db.GetCollectionSummary()
+		if err != nil {
+			writeApiError(w, err)
+			return
+		}
+
+		writeApiResponse(w, filterCollectionSummaries(summaries, parseTenantIds(r.URL.Query().Get("tenant"))))
+	})
+
+	api.HandleFunc("GET /api/summary", func(w http.ResponseWriter, r *http.Request) {
+		options, err := summaryOptionsFromQuery(r)
+		if err != nil {
+			writeApiError(w, badRequest{err})
+			return
+		}
+
+		summary, grouping, err := summarise(db, options)
+		if err != nil {
+			writeApiError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err = formats.WriteJsonReport(w, summary, grouping); err != nil {
+			log.Printf("Unable to write summary response: %s", err.Error())
+		}
+	})
+
+	api.HandleFunc("GET /api/", func(w http.ResponseWriter, r *http.Request) {
+		writeApiError(w, notFound{fmt.Errorf("%s not found", r.URL.Path)})
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/", requireToken(api))
+	mux.HandleFunc("GET /api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openApiDocument)
+	})
+
+	if serveUi {
+		web, _ := fs.Sub(webContent, "web")
+		files := http.FileServerFS(web)
+		mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Security-Policy", "default-src 'self'")
+			files.ServeHTTP(w, r)
+		}))
+	}
+
+	return mux
+}
+
+// requireToken rejects requests which do not provide the API token as a bearer token. When no token has been set
+// every request is allowed.
+func requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(apiToken) > 0 {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="azcosts"`)
+				writeApiError(w, unauthorized{errors.New("a valid bearer token must be provided")})
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// summaryOptionsFromQuery reads the summary options from the query string, using the same names and defaults as the
+// arguments of generate.
+func summaryOptionsFromQuery(r *http.Request) (summaryOptions, error) {
+	query := r.URL.Query()
+
+	options := summaryOptions{
+		level:   strings.ToLower(query.Get("by")),
+		tenants: parseTenantIds(query.Get("tenant")),
+	}
+
+	if len(options.level) == 0 {
+		options.level = ResourceGroupLevel
+	} else if !slices.Contains([]string{ResourceGroupLevel, ManagementGroupLevel, TenantLevel}, options.level) {
+		return options, fmt.Errorf("by must be one of '%s', '%s', or '%s'", ResourceGroupLevel, ManagementGroupLevel, TenantLevel)
+	}
+
+	var err error
+	if options.months, err = queryInt(r, "months", 6); err != nil {
+		return options, err
+	} else if options.months <= 0 {
+		return options, fmt.Errorf("months must be greater than 0")
+	}
+
+	if options.forecast, err = queryInt(r, "forecast", 0); err != nil {
+		return options, err
+	} else if options.forecast < 0 {
+		return options, fmt.Errorf("forecast cannot be negative")
+	}
+
+	if options.project, err = queryBool(r, "project"); err != nil {
+		return options, err
+	}
+
+	if options.azureForecast, err = queryBool(r, "azure-forecast"); err != nil {
+		return options, err
+	} else if options.azureForecast && options.level == ManagementGroupLevel {
+		return options, fmt.Errorf("azure forecasts cannot be included when summarising by management group")
+	}
+
+	return options, nil
+}
+
+func queryInt(r *http.Request, name string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if len(value) == 0 {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a whole number", name)
+	}
+	return i, nil
+}
+
+func queryBool(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if len(value) == 0 {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be either true or false", name)
+	}
+	return b, nil
+}
+
+type badRequest struct{ error }
+
+type unauthorized struct{ error }
+
+type notFound struct{ error }
+
+// writeApiResponse writes the value as JSON, writing empty lists rather than null so that clients do not need to
+// handle both.
+func writeApiResponse(w http.ResponseWriter, value any) {
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Slice && v.IsNil() {
+		value = []any{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Unable to write response: %s", err.Error())
+	}
+}
+
+// writeApiError writes the error as a JSON object, using the type of the error to determine the status code. Errors
+// from the store which indicate that no costs have been collected are returned as not found.
+func writeApiError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+
+	switch {
+	case errors.As(err, &badRequest{}):
+		status = http.StatusBadRequest
+	case errors.As(err, &unauthorized{}):
+		status = http.StatusUnauthorized
+	case errors.As(err, &notFound{}), errors.Is(err, sqlite.ErrNoCostData), errors.Is(err, sqlite.ErrNoTenantCostData):
+		status = http.StatusNotFound
+	default:
+		log.Printf("Unable to handle request: %s", err.Error())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"encoding/json"
+	"github.com/dazfuller/azcosts/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApiHandler(t *testing.T) {
+	now := time.Now().UTC()
+	cost := model.ResourceGroupCost{
+		SubscriptionId:   "00000000-0000-0000-0000-000000000001",
+		SubscriptionName: "Production",
+		TenantId:         "aaaaaaaa-0000-0000-0000-000000000000",
+		Name:             "rg-web",
+		BillingPeriod:    time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		Cost:             12.5,
+		CostUSD:          15,
+		Currency:         "GBP",
+	}
+
+	tests := []struct {
+		name      string
+		costs     []model.ResourceGroupCost
+		token     string
+		path      string
+		header    string
+		status    int
+		wantError string
+	}{
+		{
+			name:      "missing token",
+			costs:     []model.ResourceGroupCost{cost},
+			token:     "secret",
+			path:      "/api/summary",
+			status:    http.StatusUnauthorized,
+			wantError: "a valid bearer token must be provided",
+		},
+		{
+			name:      "invalid token",
+			costs:     []model.ResourceGroupCost{cost},
+			token:     "secret",
+			path:      "/api/summary",
+			header:    "Bearer guess",
+			status:    http.StatusUnauthorized,
+			wantError: "a valid bearer token must be provided",
+		},
+		{
+			name:   "valid token",
+			costs:  []model.ResourceGroupCost{cost},
+			token:  "secret",
+			path:   "/api/summary",
+			header: "Bearer secret",
+			status: http.StatusOK,
+		},
+		{
+			name:      "months is not a number",
+			costs:     []model.ResourceGroupCost{cost},
+			path:      "/api/summary?months=six",
+			status:    http.StatusBadRequest,
+			wantError: "months must be a whole number",
+		},
+		{
+			name:      "months is not positive",
+			costs:     []model.ResourceGroupCost{cost},
+			path:      "/api/billing-periods?months=0",
+			status:    http.StatusBadRequest,
+			wantError: "months must be greater than 0",
+		},
+		{
+			name:      "unknown level",
+			costs:     []model.ResourceGroupCost{cost},
+			path:      "/api/summary?by=region",
+			status:    http.StatusBadRequest,
+			wantError: "by must be one of",
+		},
+		{
+			name:      "azure forecasts by management group",
+			costs:     []model.ResourceGroupCost{cost},
+			path:      "/api/summary?by=management-group&azure-forecast=true",
+			status:    http.StatusBadRequest,
+			wantError: "azure forecasts cannot be included when summarising by management group",
+		},
+		{
+			name:      "no costs collected",
+			path:      "/api/summary",
+			status:    http.StatusNotFound,
+			wantError: "no cost data has yet been collected",
+		},
+		{
+			name:      "unknown endpoint",
+			path:      "/api/unknown",
+			status:    http.StatusNotFound,
+			wantError: "/api/unknown not found",
+		},
+		{
+			name:   "openapi document does not require a token",
+			token:  "secret",
+			path:   "/api/openapi.json",
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &apiToken, tt.token)
+			setFlag(t, &serveUi, false)
+			db := newTestStore(t, tt.costs...)
+
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if len(tt.header) > 0 {
+				request.Header.Set("Authorization", tt.header)
+			}
+			response := httptest.NewRecorder()
+			apiHandler(db).ServeHTTP(response, request)
+
+			if response.Code != tt.status {
+				t.Fatalf("%s status = %d, want %d: %s", tt.path, response.Code, tt.status, response.Body.String())
+			}
+			if contentType := response.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("%s content type = %q, want application/json", tt.path, contentType)
+			}
+
+			if len(tt.wantError) > 0 {
+				var body map[string]string
+				if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(body["error"], tt.wantError) {
+					t.Errorf("%s error = %q, want %q", tt.path, body["error"], tt.wantError)
+				}
+			}
+		})
+	}
+}
+
+func TestApiSummary(t *testing.T) {
+	now := time.Now().UTC()
+	period := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	setFlag(t, &apiToken, "")
+	setFlag(t, &serveUi, false)
+	db := newTestStore(t,
+		model.ResourceGroupCost{SubscriptionId: "00000000-0000-0000-0000-000000000001", SubscriptionName: "Production", TenantId: "aaaaaaaa-0000-0000-0000-000000000000", Name: "rg-web", BillingPeriod: period, Cost: 12.5, Currency: "GBP"},
+		model.ResourceGroupCost{SubscriptionId: "00000000-0000-0000-0000-000000000002", SubscriptionName: "Development", TenantId: "bbbbbbbb-0000-0000-0000-000000000000", Name: "rg-web", BillingPeriod: period, Cost: 2.5, Currency: "GBP"},
+	)
+
+	tests := []struct {
+		name    string
+		path    string
+		groupBy string
+		count   int
+		total   float64
+	}{
+		{name: "resource groups", path: "/api/summary", groupBy: "Resource Group", count: 2, total: 15},
+		{name: "tenant filter", path: "/api/summary?tenant=AAAAAAAA-0000-0000-0000-000000000000", groupBy: "Resource Group", count: 1, total: 12.5},
+		{name: "tenants", path: "/api/summary?by=tenant", groupBy: "Subscription", count: 2, total: 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+			apiHandler(db).ServeHTTP(response, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if response.Code != http.StatusOK {
+				t.Fatalf("%s status = %d, want %d: %s", tt.path, response.Code, http.StatusOK, response.Body.String())
+			}
+
+			var report struct {
+				GroupBy            string  `json:"groupBy"`
+				ResourceGroupCount int     `json:"resourceGroupCount"`
+				TotalCost          float64 `json:"totalCost"`
+			}
+			if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if report.GroupBy != tt.groupBy || report.ResourceGroupCount != tt.count || report.TotalCost != tt.total {
+				t.Errorf("%s = %+v, want %s with %d rows costing %v", tt.path, report, tt.groupBy, tt.count, tt.total)
+			}
+		})
+	}
+}
+
+func TestDashboardContentSecurityPolicy(t *testing.T) {
+	setFlag(t, &apiToken, "secret")
+	setFlag(t, &serveUi, true)
+	db := newTestStore(t)
+
+	response := httptest.NewRecorder()
+	apiHandler(db).ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("/ status = %d, want %d", response.Code, http.StatusOK)
+	}
+	if csp := response.Header().Get("Content-Security-Policy"); csp != "default-src 'self'" {
+		t.Errorf("/ Content-Security-Policy = %q, want default-src 'self'", csp)
+	}
+}
@@ -0,0 +1,366 @@
+"use strict";
+
+// parentLabels maps the name of each grouping to the label of its parent, matching the groupings used by generate.
+var parentLabels = {
+  "Resource Group": "Subscription",
+  "Management Group": "Parent Management Group",
+  "Subscription": "Tenant"
+};
+
+var state = {
+  report: null,
+  parent: null,
+  group: null
+};
+
+function $(id) {
+  return document.getElementById(id);
+}
+
+function formatCost(value) {
+  return value.toLocaleString(undefined, { minimumFractionDigits: 2, maximumFractionDigits: 2 });
+}
+
+function periodLabel(cost) {
+  if (cost.forecast && cost.source === "azure") {
+    return cost.period + " (AF)";
+  } else if (cost.forecast) {
+    return cost.period + " (F)";
+  }
+  return cost.period;
+}
+
+function element(tag, text, className) {
+  var el = document.createElement(tag);
+  if (text !== undefined && text !== null) {
+    el.textContent = text;
+  }
+  if (className) {
+    el.className = className;
+  }
+  return el;
+}
+
+function showMessage(text) {
+  $("message").textContent = text;
+  $("message").hidden = !text;
+}
+
+// api requests the given path, adding the access token where one has been entered. Requests which are rejected
+// because a token is required show the sign in form.
+function api(path) {
+  var headers = {};
+  var token = sessionStorage.getItem("azcosts-token");
+  if (token) {
+    headers["Authorization"] = "Bearer " + token;
+  }
+
+  return fetch(path, { headers: headers }).then(function (response) {
+    if (response.status === 401) {
+      $("login").hidden = false;
+      $("dashboard").hidden = true;
+      throw new Error("unauthorized");
+    }
+    return response.json().then(function (body) {
+      if (!response.ok) {
+        throw new Error(body.error || response.statusText);
+      }
+      return body;
+    });
+  });
+}
+
+function loadTenants() {
+  return api("api/subscriptions").then(function (subscriptions) {
+    var select = $("tenant");
+    var tenants = [];
+    subscriptions.forEach(function (s) {
+      if (s.tenantId && tenants.indexOf(s.tenantId) < 0) {
+        tenants.push(s.tenantId);
+      }
+    });
+    tenants.sort().forEach(function (tenant) {
+      var option = element("option", tenant);
+      option.value = tenant;
+      select.appendChild(option);
+    });
+  });
+}
+
+function loadSummary() {
+  var query = new URLSearchParams({
+    months: $("months").value,
+    by: $("level").value,
+    project: $("project").checked
+  });
+  if ($("tenant").value) {
+    query.set("tenant", $("tenant").value);
+  }
+
+  return api("api/summary?" + query.toString()).then(function (report) {
+    state.report = report;
+    state.parent = null;
+    state.group = null;
+    $("login").hidden = true;
+    showMessage("");
+    render();
+  }).catch(function (err) {
+    if (err.message !== "unauthorized") {
+      $("dashboard").hidden = true;
+      showMessage(err.message);
+    }
+  });
+}
+
+// totals returns the total of each billing period across the summaries.
+function totals(summaries, periods) {
+  return periods.map(function (p, i) {
+    return {
+      period: p.period,
+      forecast: p.forecast,
+      source: p.source,
+      total: summaries.reduce(function (sum, s) { return sum + s.costs[i].total; }, 0)
+    };
+  });
+}
+
+function render() {
+  var report = state.report;
+  var groups = report.resourceGroups || [];
+  var periods = groups.length > 0 ? groups[0].costs : [];
+  var parentLabel = parentLabels[report.groupBy] || "Parent";
+
+  var visible = groups.filter(function (g) {
+    return state.parent === null || g.subscriptionName === state.parent;
+  });
+
+  $("dashboard").hidden = false;
+
+  renderBreadcrumb(report.groupBy, parentLabel);
+
+  var totalCost = visible.reduce(function (sum, g) { return sum + g.totalCost; }, 0);
+  var actual = periods.filter(function (p) { return !p.forecast; });
+  $("total-cost").textContent = formatCost(totalCost);
+  $("count-label").textContent = report.groupBy + "s";
+  $("count").textContent = visible.length;
+  $("latest").textContent = actual.length > 0 ? actual[actual.length - 1].period : "-";
+
+  var chartCosts;
+  if (state.group !== null) {
+    $("chart-title").textContent = state.group.name + " by billing period";
+    chartCosts = state.group.costs;
+  } else if (state.parent !== null) {
+    $("chart-title").textContent = state.parent + " by billing period";
+    chartCosts = totals(visible, periods);
+  } else {
+    $("chart-title").textContent = "Total cost by billing period";
+    chartCosts = totals(visible, periods);
+  }
+  $("chart").replaceChildren(barChart(chartCosts));
+
+  renderParents(report, periods, parentLabel);
+  renderGroups(visible, periods, report.groupBy, parentLabel);
+}
+
+function renderBreadcrumb(groupBy, parentLabel) {
+  var nav = $("breadcrumb");
+  nav.replaceChildren();
+
+  var all = element("a", "All " + parentLabel.toLowerCase() + "s");
+  all.addEventListener("click", function () {
+    state.parent = null;
+    state.group = null;
+    render();
+  });
+  nav.appendChild(all);
+
+  if (state.parent !== null) {
+    nav.appendChild(document.createTextNode(" / "));
+    var parent = element("a", state.parent);
+    parent.addEventListener("click", function () {
+      state.group = null;
+      render();
+    });
+    nav.appendChild(parent);
+  }
+
+  if (state.group !== null) {
+    nav.appendChild(document.createTextNode(" / " + state.group.name));
+  }
+}
+
+function headerRow(columns, periods) {
+  var row = element("tr");
+  columns.forEach(function (c) { row.appendChild(element("th", c)); });
+  periods.forEach(function (p) {
+    row.appendChild(element("th", periodLabel(p), p.forecast ? "num forecast" : "num"));
+  });
+  row.appendChild(element("th", "Total Costs", "num"));
+  return row;
+}
+
+function costCells(row, costs, totalCost) {
+  costs.forEach(function (c) {
+    var td = element("td", formatCost(c.total), c.forecast ? "num forecast" : "num");
+    td.dataset.value = c.total;
+    row.appendChild(td);
+  });
+  var total = element("td", formatCost(totalCost), "num");
+  total.dataset.value = totalCost;
+  row.appendChild(total);
+}
+
+function renderParents(report, periods, parentLabel) {
+  $("parents-title").textContent = parentLabel + " totals";
+
+  var table = $("parents");
+  table.tHead.replaceChildren(headerRow([parentLabel, "Trend"], periods));
+
+  var subscriptions = (report.subscriptions || []).slice().sort(function (a, b) {
+    return a.name.localeCompare(b.name);
+  });
+
+  var body = table.tBodies[0];
+  body.replaceChildren();
+  subscriptions.forEach(function (s) {
+    var row = element("tr", null, s.name === state.parent ? "link selected" : "link");
+    row.appendChild(element("td", s.name));
+    var trend = element("td");
+    trend.appendChild(sparkline(s.costs));
+    row.appendChild(trend);
+    costCells(row, s.costs, s.totalCost);
+    row.addEventListener("click", function () {
+      state.parent = s.name;
+      state.group = null;
+      render();
+    });
+    body.appendChild(row);
+  });
+}
+
+function renderGroups(groups, periods, groupBy, parentLabel) {
+  $("groups-title").textContent = groupBy + " costs";
+
+  var table = $("groups");
+  table.tHead.replaceChildren(headerRow([groupBy, parentLabel, "Active", "Trend"], periods));
+  makeSortable(table);
+
+  var body = table.tBodies[0];
+  body.replaceChildren();
+  groups.forEach(function (g) {
+    var selected = state.group !== null && state.group.name === g.name && state.group.subscriptionName === g.subscriptionName;
+    var row = element("tr", null, selected ? "link selected" : "link");
+    row.appendChild(element("td", g.name));
+    row.appendChild(element("td", g.subscriptionName));
+    row.appendChild(element("td", g.active ? "Yes" : "No"));
+    var trend = element("td");
+    trend.appendChild(sparkline(g.costs));
+    row.appendChild(trend);
+    costCells(row, g.costs, g.totalCost);
+    row.addEventListener("click", function () {
+      state.parent = g.subscriptionName;
+      state.group = g;
+      render();
+    });
+    body.appendChild(row);
+  });
+}
+
+function makeSortable(table) {
+  var headers = table.tHead.querySelectorAll("th");
+  headers.forEach(function (th, column) {
+    th.addEventListener("click", function () {
+      var ascending = !th.classList.contains("asc");
+      headers.forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(ascending ? "asc" : "desc");
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[column], y = b.cells[column];
+        var c = x.dataset.value !== undefined
+          ? parseFloat(x.dataset.value) - parseFloat(y.dataset.value)
+          : x.textContent.localeCompare(y.textContent);
+        return ascending ? c : -c;
+      });
+      rows.forEach(function (row) { body.appendChild(row); });
+    });
+  });
+}
+
+var svgNS = "http://www.w3.org/2000/svg";
+
+function svgElement(tag, attributes) {
+  var el = document.createElementNS(svgNS, tag);
+  Object.keys(attributes).forEach(function (name) {
+    el.setAttribute(name, attributes[name]);
+  });
+  return el;
+}
+
+function barChart(costs) {
+  var width = Math.max(360, costs.length * 70), height = 220, labelHeight = 20;
+  var plotHeight = height - labelHeight;
+  var max = Math.max.apply(null, costs.map(function (c) { return c.total; }).concat([0]));
+  var slot = width / Math.max(costs.length, 1);
+  var barWidth = slot * 0.6;
+
+  var svg = svgElement("svg", { width: width, height: height, viewBox: "0 0 " + width + " " + height, role: "img" });
+
+  costs.forEach(function (c, i) {
+    var barHeight = max > 0 ? Math.max(c.total, 0) / max * (plotHeight - 20) : 0;
+    var x = i * slot + (slot - barWidth) / 2;
+    var bar = svgElement("rect", {
+      "class": c.forecast ? "bar forecast" : "bar",
+      x: x, y: plotHeight - barHeight, width: barWidth, height: barHeight
+    });
+    var title = svgElement("title", {});
+    title.textContent = periodLabel(c) + ": " + formatCost(c.total);
+    bar.appendChild(title);
+    svg.appendChild(bar);
+
+    var value = svgElement("text", { x: i * slot + slot / 2, y: plotHeight - barHeight - 4, "text-anchor": "middle" });
+    value.textContent = formatCost(c.total);
+    svg.appendChild(value);
+
+    var label = svgElement("text", { x: i * slot + slot / 2, y: height - 4, "text-anchor": "middle" });
+    label.textContent = periodLabel(c);
+    svg.appendChild(label);
+  });
+
+  svg.appendChild(svgElement("line", { "class": "axis", x1: 0, y1: plotHeight, x2: width, y2: plotHeight }));
+  return svg;
+}
+
+function sparkline(costs) {
+  var width = 80, height = 20;
+  var values = costs.map(function (c) { return c.total; });
+  var max = Math.max.apply(null, values.concat([0]));
+  var step = values.length > 1 ? width / (values.length - 1) : 0;
+
+  var points = values.map(function (v, i) {
+    var y = max > 0 ? height - 2 - (Math.max(v, 0) / max) * (height - 4) : height - 2;
+    return (i * step).toFixed(1) + "," + y.toFixed(1);
+  });
+
+  var svg = svgElement("svg", { "class": "spark", width: width, height: height, viewBox: "0 0 " + width + " " + height });
+  svg.appendChild(svgElement("polyline", { points: points.join(" ") }));
+  return svg;
+}
+
+document.addEventListener("DOMContentLoaded", function () {
+  ["months", "level", "tenant", "project"].forEach(function (id) {
+    $(id).addEventListener("change", loadSummary);
+  });
+
+  $("login-form").addEventListener("submit", function (e) {
+    e.preventDefault();
+    sessionStorage.setItem("azcosts-token", $("token").value);
+    loadTenants().then(loadSummary).catch(function () {});
+  });
+
+  loadTenants().then(loadSummary).catch(function (err) {
+    if (err.message !== "unauthorized") {
+      showMessage(err.message);
+    }
+  });
+});
@@ -0,0 +1,103 @@
+package analysis
+
+import (
+	"github.com/dazfuller/azcosts/internal/model"
+	"math"
+)
+
+// AnomalyOptions controls the thresholds used when detecting cost anomalies.
+type AnomalyOptions struct {
+	// StdDevThreshold is the number of standard deviations above the historic mean the latest period must exceed
+	// to be reported as a spike. A value of 0 or less disables the check.
+	StdDevThreshold float64
+
+	// PercentThreshold is the percentage increase over the historic mean the latest period must exceed to be
+	// reported as an increase. A value of 0 or less disables the check.
+	PercentThreshold float64
+
+	// MinimumCost is the smallest cost considered significant, costs below this value are never reported.
+	MinimumCost float64
+}
+
+// DetectAnomalies compares the latest billing period of each resource group against the preceding periods and
+// returns those which have spiked, increased beyond the percentage threshold, appeared for the first time, or
+// have stopped incurring costs.
+//
+// Subscriptions with no costs in the latest period are skipped, as this usually means the period has not yet been
+// collected for them rather than every resource group having vanished.
+func DetectAnomalies(costs []model.ResourceGroupSummary, options AnomalyOptions) []model.Anomaly {
+	latestSubscriptionCosts := make(map[string]float64)
+	for _, rg := range costs {
+		if len(rg.Costs) > 0 {
+			latestSubscriptionCosts[rg.SubscriptionId] += rg.Costs[len(rg.Costs)-1].Total
+		}
+	}
+
+	anomalies := make([]model.Anomaly, 0)
+
+	for _, rg := range costs {
+		if len(rg.Costs) < 2 || latestSubscriptionCosts[rg.SubscriptionId] == 0 {
+			continue
+		}
+
+		latest := rg.Costs[len(rg.Costs)-1]
+		history := rg.Costs[:len(rg.Costs)-1]
+		previous := history[len(history)-1].Total
+		mean, stdDev := meanAndStdDev(history)
+
+		anomaly := model.Anomaly{
+			ResourceGroup:    rg.Name,
+			SubscriptionName: rg.SubscriptionName,
+			Period:           latest.Period,
+			Cost:             latest.Total,
+			PreviousCost:     previous,
+			Mean:             mean,
+			StdDev:           stdDev,
+		}
+
+		if mean > 0 {
+			anomaly.Change = (latest.Total - mean) / mean * 100
+		}
+
+		switch {
+		case mean == 0 && latest.Total >= options.MinimumCost && latest.Total > 0:
+			anomaly.Kind = model.AnomalyNew
+		case math.Round(latest.Total*100) == 0 && previous >= options.MinimumCost && previous > 0:
+			anomaly.Kind = model.AnomalyVanished
+			anomaly.Change = -100
+		case latest.Total < options.MinimumCost:
+			continue
+		case options.StdDevThreshold > 0 && stdDev > 0 && latest.Total > mean+options.StdDevThreshold*stdDev:
+			anomaly.Kind = model.AnomalySpike
+		case options.PercentThreshold > 0 && mean > 0 && anomaly.Change >= options.PercentThreshold:
+			anomaly.Kind = model.AnomalyIncrease
+		default:
+			continue
+		}
+
+		anomalies = append(anomalies, anomaly)
+	}
+
+	return anomalies
+}
+
+// meanAndStdDev returns the mean and population standard deviation of the billing period totals.
+func meanAndStdDev(costs []model.BillingPeriodCost) (float64, float64) {
+	if len(costs) == 0 {
+		return 0, 0
+	}
+
+	total := float64(0)
+	for _, c := range costs {
+		total += c.Total
+	}
+	mean := total / float64(len(costs))
+
+	variance := float64(0)
+	for _, c := range costs {
+		variance += math.Pow(c.Total-mean, 2)
+	}
+	variance /= float64(len(costs))
+
+	return mean, math.Sqrt(variance)
+}
@@ -0,0 +1,164 @@
+package analysis
+
+import (
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"math"
+	"testing"
+)
+
+// periodCosts returns a billing period for each total, starting from January 2024.
+func periodCosts(totals ...float64) []model.BillingPeriodCost {
+	costs := make([]model.BillingPeriodCost, len(totals))
+	for i, total := range totals {
+		costs[i] = model.BillingPeriodCost{Period: fmt.Sprintf("2024-%02d", i+1), Total: total}
+	}
+	return costs
+}
+
+func TestMeanAndStdDev(t *testing.T) {
+	tests := []struct {
+		name   string
+		costs  []model.BillingPeriodCost
+		mean   float64
+		stdDev float64
+	}{
+		{name: "no periods", costs: nil, mean: 0, stdDev: 0},
+		{name: "single period", costs: periodCosts(42), mean: 42, stdDev: 0},
+		{name: "constant costs", costs: periodCosts(10, 10, 10), mean: 10, stdDev: 0},
+		{name: "population deviation", costs: periodCosts(2, 4, 4, 4, 5, 5, 7, 9), mean: 5, stdDev: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mean, stdDev := meanAndStdDev(tt.costs)
+			if math.Abs(mean-tt.mean) > 1e-9 || math.Abs(stdDev-tt.stdDev) > 1e-9 {
+				t.Errorf("meanAndStdDev() = %v, %v, want %v, %v", mean, stdDev, tt.mean, tt.stdDev)
+			}
+		})
+	}
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	options := AnomalyOptions{StdDevThreshold: 3, PercentThreshold: 50, MinimumCost: 10}
+
+	tests := []struct {
+		name    string
+		costs   []model.ResourceGroupSummary
+		options AnomalyOptions
+		want    map[string]string
+	}{
+		{
+			name: "spike above standard deviations",
+			costs: []model.ResourceGroupSummary{
+				{Name: "rg-spike", SubscriptionId: "sub-1", SubscriptionName: "sub", Costs: periodCosts(100, 100, 110, 90, 200)},
+			},
+			options: options,
+			want:    map[string]string{"rg-spike": model.AnomalySpike},
+		},
+		{
+			name: "increase above percentage when there is no deviation",
+			costs: []model.ResourceGroupSummary{
+				{Name: "rg-increase", SubscriptionId: "sub-1", SubscriptionName: "sub", Costs: periodCosts(100, 100, 100, 160)},
+				{Name: "rg-steady", SubscriptionId: "sub-1", SubscriptionName: "sub", Costs: periodCosts(100, 100, 100, 120)},
+			},
+			options: options,
+			want:    map[string]string{"rg-increase": model.AnomalyIncrease},
+		},
+		{
+			name: "disabled thresholds",
+			costs: []model.ResourceGroupSummary{
+				{Name: "rg-spike", SubscriptionId: "sub-1", SubscriptionName: "sub", Costs: periodCosts(100, 100, 110, 90, 200)},
+			},
+			options: AnomalyOptions{MinimumCost: 10},
+			want:    map[string]string{},
+		},
+		{
+			name: "new resource groups above the minimum cost",
+			costs: []model.ResourceGroupSummary{
+				{Name: "rg-new", SubscriptionId: "sub-1", SubscriptionName: "sub", Costs: periodCosts(0, 0, 50)},
+				{Name: "rg-small", SubscriptionId: "sub-1", SubscriptionName: "sub", Costs: periodCosts(0, 0, 5)},
+			},
+			options: options,
+			want:    map[string]string{"rg-new": model.AnomalyNew},
+		},
+		{
+			name: "vanished resource groups",
+			costs: []model.ResourceGroupSummary{
+				{Name: "rg-vanished", SubscriptionId: "sub-1", SubscriptionName: "sub", Costs: periodCosts(100, 80, 0.001)},
+				{Name: "rg-other", SubscriptionId: "sub-1", SubscriptionName: "sub", Costs: periodCosts(100, 100, 100)},
+			},
+			options: options,
+			want:    map[string]string{"rg-vanished": model.AnomalyVanished},
+		},
+		{
+			name: "subscriptions without costs in the latest period are skipped",
+			costs: []model.ResourceGroupSummary{
+				{Name: "rg-a", SubscriptionId: "sub-2", SubscriptionName: "uncollected", Costs: periodCosts(100, 80, 0)},
+				{Name: "rg-b", SubscriptionId: "sub-2", SubscriptionName: "uncollected", Costs: periodCosts(50, 50, 0)},
+			},
+			options: options,
+			want:    map[string]string{},
+		},
+		{
+			name: "subscriptions with the same name are kept separate",
+			costs: []model.ResourceGroupSummary{
+				{Name: "rg-collected", SubscriptionId: "sub-1", SubscriptionName: "Production", Costs: periodCosts(100, 100, 100)},
+				{Name: "rg-uncollected", SubscriptionId: "sub-2", SubscriptionName: "Production", Costs: periodCosts(100, 80, 0)},
+			},
+			options: options,
+			want:    map[string]string{},
+		},
+		{
+			name: "resource groups without history are skipped",
+			costs: []model.ResourceGroupSummary{
+				{Name: "rg-single", SubscriptionId: "sub-1", SubscriptionName: "sub", Costs: periodCosts(500)},
+			},
+			options: options,
+			want:    map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anomalies := DetectAnomalies(tt.costs, tt.options)
+
+			got := make(map[string]string)
+			for _, a := range anomalies {
+				got[a.ResourceGroup] = a.Kind
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("DetectAnomalies() = %v, want %v", got, tt.want)
+			}
+			for name, kind := range tt.want {
+				if got[name] != kind {
+					t.Errorf("DetectAnomalies() kind of %s = %q, want %q", name, got[name], kind)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectAnomaliesValues(t *testing.T) {
+	costs := []model.ResourceGroupSummary{
+		{Name: "rg-increase", SubscriptionId: "sub-1", SubscriptionName: "sub", Costs: periodCosts(100, 100, 160)},
+		{Name: "rg-vanished", SubscriptionId: "sub-1", SubscriptionName: "sub", Costs: periodCosts(40, 60, 0)},
+	}
+
+	anomalies := DetectAnomalies(costs, AnomalyOptions{PercentThreshold: 50, MinimumCost: 10})
+	if len(anomalies) != 2 {
+		t.Fatalf("DetectAnomalies() returned %d anomalies, want 2", len(anomalies))
+	}
+
+	increase := anomalies[0]
+	if increase.Period != "2024-03" || increase.Cost != 160 || increase.PreviousCost != 100 || increase.Mean != 100 ||
+		math.Abs(increase.Change-60) > 1e-9 {
+		t.Errorf("DetectAnomalies() increase = %+v", increase)
+	}
+
+	vanished := anomalies[1]
+	if vanished.Change != -100 || vanished.PreviousCost != 60 || vanished.Mean != 50 {
+		t.Errorf("DetectAnomalies() vanished = %+v", vanished)
+	}
+}
@@ -0,0 +1,217 @@
+package analysis
+
+import (
+	"github.com/dazfuller/azcosts/internal/model"
+	"math"
+	"slices"
+	"time"
+)
+
+const periodFormat = "2006-01"
+
+// ProjectCurrentPeriod adds a forecast entry for the current billing period to each resource group, projecting the
+// month-to-date cost to the end of the month based on how far through the month the data was collected.
+//
+// Collection times are keyed by subscription id, where a summary has no collection time the current time is used
+// instead. If the current billing period has not been collected then the costs are returned unchanged.
+func ProjectCurrentPeriod(costs []model.ResourceGroupSummary, collectionTimes map[string]time.Time, now time.Time) []model.ResourceGroupSummary {
+	now = now.UTC()
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodLength := periodStart.AddDate(0, 1, 0).Sub(periodStart)
+	currentPeriod := periodStart.Format(periodFormat)
+
+	projected := make([]model.ResourceGroupSummary, len(costs))
+
+	for i, rg := range costs {
+		projected[i] = rg
+		projected[i].Costs = slices.Clone(rg.Costs)
+
+		if len(rg.Costs) == 0 {
+			continue
+		}
+
+		latest := rg.Costs[len(rg.Costs)-1]
+		if latest.Forecast || latest.Period != currentPeriod {
+			continue
+		}
+
+		collectedAt, ok := collectionTimes[rg.SubscriptionId]
+		if !ok {
+			collectedAt = now
+		}
+
+		elapsed := collectedAt.Sub(periodStart)
+		if elapsed < time.Hour*24 {
+			elapsed = time.Hour * 24
+		} else if elapsed > periodLength {
+			elapsed = periodLength
+		}
+
+		projected[i].Costs = append(projected[i].Costs, model.BillingPeriodCost{
+			Period:   currentPeriod,
+			Total:    latest.Total * (float64(periodLength) / float64(elapsed)),
+			Forecast: true,
+		})
+	}
+
+	return projected
+}
+
+// ForecastPeriods adds forecast entries for the given number of months following the last billing period of each
+// resource group. Forecasts are made using a linear trend fitted to the completed billing periods, where a billing
+// period is complete if it is before the current month and is not itself a forecast.
+func ForecastPeriods(costs []model.ResourceGroupSummary, months int, now time.Time) []model.ResourceGroupSummary {
+	now = now.UTC()
+	currentPeriod := now.Format(periodFormat)
+
+	forecast := make([]model.ResourceGroupSummary, len(costs))
+
+	for i, rg := range costs {
+		forecast[i] = rg
+		forecast[i].Costs = slices.Clone(rg.Costs)
+
+		if len(rg.Costs) == 0 || months <= 0 {
+			continue
+		}
+
+		origin, err := time.Parse(periodFormat, rg.Costs[0].Period)
+		if err != nil {
+			continue
+		}
+
+		var x, y []float64
+		for _, bp := range rg.Costs {
+			if bp.Forecast || bp.Period >= currentPeriod {
+				continue
+			}
+
+			period, err := time.Parse(periodFormat, bp.Period)
+			if err != nil {
+				continue
+			}
+
+			x = append(x, float64(monthsBetween(origin, period)))
+			y = append(y, bp.Total)
+		}
+
+		intercept, slope := linearTrend(x, y)
+
+		last, err := time.Parse(periodFormat, rg.Costs[len(rg.Costs)-1].Period)
+		if err != nil {
+			continue
+		}
+
+		for m := 1; m <= months; m++ {
+			period := last.AddDate(0, m, 0)
+			total := intercept + slope*float64(monthsBetween(origin, period))
+
+			forecast[i].Costs = append(forecast[i].Costs, model.BillingPeriodCost{
+				Period:   period.Format(periodFormat),
+				Total:    math.Max(total, 0),
+				Forecast: true,
+			})
+		}
+	}
+
+	return forecast
+}
+
+// AllocateForecasts adds the forecasts collected from Cost Management to each resource group as forecast periods. As
+// these forecasts are only available for a subscription as a whole, each resource group is allocated a share of the
+// subscription forecast in proportion to its share of the subscription cost in the latest completed billing period.
+// Where the subscription had no cost in that period the forecast is shared equally between its resource groups.
+func AllocateForecasts(costs []model.ResourceGroupSummary, forecasts []model.CostForecast, now time.Time) []model.ResourceGroupSummary {
+	currentPeriod := now.UTC().Format(periodFormat)
+
+	subscriptionForecasts := make(map[string]map[string]float64)
+	var periods []string
+	for _, f := range forecasts {
+		period := f.BillingPeriod.Format(periodFormat)
+		if _, ok := subscriptionForecasts[f.SubscriptionId]; !ok {
+			subscriptionForecasts[f.SubscriptionId] = make(map[string]float64)
+		}
+		subscriptionForecasts[f.SubscriptionId][period] += f.Cost
+
+		if !slices.Contains(periods, period) {
+			periods = append(periods, period)
+		}
+	}
+	slices.Sort(periods)
+
+	basis := -1
+	if len(costs) > 0 {
+		for i, bp := range costs[0].Costs {
+			if bp.Forecast {
+				continue
+			}
+			if basis < 0 || bp.Period < currentPeriod {
+				basis = i
+			}
+		}
+	}
+
+	subscriptionTotals := make(map[string]float64)
+	subscriptionGroups := make(map[string]int)
+	for _, rg := range costs {
+		if basis >= 0 && basis < len(rg.Costs) {
+			subscriptionTotals[rg.SubscriptionId] += rg.Costs[basis].Total
+		}
+		subscriptionGroups[rg.SubscriptionId]++
+	}
+
+	allocated := make([]model.ResourceGroupSummary, len(costs))
+
+	for i, rg := range costs {
+		allocated[i] = rg
+		allocated[i].Costs = slices.Clone(rg.Costs)
+
+		share := 1 / float64(subscriptionGroups[rg.SubscriptionId])
+		if total := subscriptionTotals[rg.SubscriptionId]; total > 0 {
+			share = rg.Costs[basis].Total / total
+		}
+
+		for _, period := range periods {
+			allocated[i].Costs = append(allocated[i].Costs, model.BillingPeriodCost{
+				Period:   period,
+				Total:    subscriptionForecasts[rg.SubscriptionId][period] * share,
+				Forecast: true,
+				Source:   "azure",
+			})
+		}
+	}
+
+	return allocated
+}
+
+// linearTrend returns the intercept and slope of the least squares line through the given points. With a single
+// point the trend is flat, and with no points both values are 0.
+func linearTrend(x []float64, y []float64) (float64, float64) {
+	n := float64(len(x))
+	if n == 0 {
+		return 0, 0
+	} else if n == 1 {
+		return y[0], 0
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+		sumXY += x[i] * y[i]
+		sumXX += x[i] * x[i]
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return sumY / n, 0
+	}
+
+	slope := (n*sumXY - sumX*sumY) / denominator
+	intercept := (sumY - slope*sumX) / n
+
+	return intercept, slope
+}
+
+func monthsBetween(from time.Time, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
@@ -0,0 +1,160 @@
+package analysis
+
+import (
+	"github.com/dazfuller/azcosts/internal/model"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestProjectCurrentPeriod(t *testing.T) {
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		costs       []model.BillingPeriodCost
+		collectedAt map[string]time.Time
+		want        float64
+		projected   bool
+	}{
+		{
+			name:        "projects from the collection time",
+			costs:       periodCosts(100, 100, 50),
+			collectedAt: map[string]time.Time{"sub-1": time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+			want:        50 * 31.0 / 10.0,
+			projected:   true,
+		},
+		{
+			name:        "uses the current time without a collection time",
+			costs:       periodCosts(100, 100, 50),
+			collectedAt: map[string]time.Time{},
+			want:        50 * 31.0 / 19.5,
+			projected:   true,
+		},
+		{
+			name:        "collection times are keyed by subscription id",
+			costs:       periodCosts(100, 100, 50),
+			collectedAt: map[string]time.Time{"tenant-a": time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+			want:        50 * 31.0 / 19.5,
+			projected:   true,
+		},
+		{
+			name:        "projects at least a day of costs",
+			costs:       periodCosts(100, 100, 5),
+			collectedAt: map[string]time.Time{"sub-1": time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)},
+			want:        5 * 31.0,
+			projected:   true,
+		},
+		{
+			name:        "current period not collected",
+			costs:       periodCosts(100, 100),
+			collectedAt: map[string]time.Time{},
+			projected:   false,
+		},
+		{
+			name:        "latest period is already a forecast",
+			costs:       append(periodCosts(100, 100, 50), model.BillingPeriodCost{Period: "2024-03", Total: 150, Forecast: true}),
+			collectedAt: map[string]time.Time{},
+			projected:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The tenant grouping uses the tenant in place of the subscription name
+			costs := []model.ResourceGroupSummary{{Name: "Production", SubscriptionId: "sub-1", SubscriptionName: "tenant-a", Costs: tt.costs}}
+			projected := ProjectCurrentPeriod(costs, tt.collectedAt, now)
+
+			if len(costs[0].Costs) != len(tt.costs) {
+				t.Fatalf("ProjectCurrentPeriod() modified the costs provided")
+			}
+
+			got := projected[0].Costs
+			if !tt.projected {
+				if len(got) != len(tt.costs) {
+					t.Errorf("ProjectCurrentPeriod() added %d periods, want none", len(got)-len(tt.costs))
+				}
+				return
+			}
+
+			if len(got) != len(tt.costs)+1 {
+				t.Fatalf("ProjectCurrentPeriod() returned %d periods, want %d", len(got), len(tt.costs)+1)
+			}
+			last := got[len(got)-1]
+			if last.Period != "2024-03" || !last.Forecast || math.Abs(last.Total-tt.want) > 1e-6 {
+				t.Errorf("ProjectCurrentPeriod() projection = %+v, want %.4f", last, tt.want)
+			}
+		})
+	}
+}
+
+func TestForecastPeriods(t *testing.T) {
+	now := time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		costs  []model.BillingPeriodCost
+		months int
+		want   []model.BillingPeriodCost
+	}{
+		{
+			name:   "linear trend",
+			costs:  periodCosts(100, 200, 300),
+			months: 2,
+			want: []model.BillingPeriodCost{
+				{Period: "2024-04", Total: 400, Forecast: true},
+				{Period: "2024-05", Total: 500, Forecast: true},
+			},
+		},
+		{
+			name:   "current and forecast periods are excluded from the trend",
+			costs:  append(periodCosts(100, 200, 300, 20), model.BillingPeriodCost{Period: "2024-04", Total: 900, Forecast: true}),
+			months: 1,
+			want: []model.BillingPeriodCost{
+				{Period: "2024-05", Total: 500, Forecast: true},
+			},
+		},
+		{
+			name:   "falling costs do not go below zero",
+			costs:  periodCosts(300, 200, 100),
+			months: 2,
+			want: []model.BillingPeriodCost{
+				{Period: "2024-04", Total: 0, Forecast: true},
+				{Period: "2024-05", Total: 0, Forecast: true},
+			},
+		},
+		{
+			name:   "single completed period is flat",
+			costs:  periodCosts(0, 0, 75)[2:],
+			months: 1,
+			want: []model.BillingPeriodCost{
+				{Period: "2024-04", Total: 75, Forecast: true},
+			},
+		},
+		{
+			name:   "no months",
+			costs:  periodCosts(100, 200, 300),
+			months: 0,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The tenant grouping uses the tenant in place of the subscription name
+			costs := []model.ResourceGroupSummary{{Name: "Production", SubscriptionId: "sub-1", SubscriptionName: "tenant-a", Costs: tt.costs}}
+			forecast := ForecastPeriods(costs, tt.months, now)
+
+			got := forecast[0].Costs[len(tt.costs):]
+			if len(got) != len(tt.want) {
+				t.Fatalf("ForecastPeriods() added %d periods, want %d", len(got), len(tt.want))
+			}
+			for i := range tt.want {
+				if got[i].Period != tt.want[i].Period || got[i].Forecast != tt.want[i].Forecast ||
+					math.Abs(got[i].Total-tt.want[i].Total) > 1e-6 {
+					t.Errorf("ForecastPeriods() period %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
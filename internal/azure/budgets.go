@@ -0,0 +1,135 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type budgetResponse struct {
+	Value []struct {
+		Id         string `json:"id"`
+		Name       string `json:"name"`
+		Type       string `json:"type"`
+		ETag       string `json:"eTag"`
+		Properties struct {
+			Category   string  `json:"category"`
+			Amount     float64 `json:"amount"`
+			TimeGrain  string  `json:"timeGrain"`
+			TimePeriod struct {
+				StartDate time.Time `json:"startDate"`
+				EndDate   time.Time `json:"endDate"`
+			} `json:"timePeriod"`
+			CurrentSpend struct {
+				Amount float64 `json:"amount"`
+				Unit   string  `json:"unit"`
+			} `json:"currentSpend"`
+		} `json:"properties"`
+	} `json:"value"`
+	NextLink string `json:"nextLink"`
+}
+
+type BudgetService struct {
+	azureService
+	apiVersion      string
+	endpoint        string
+	managementScope string
+}
+
+func NewBudgetService() BudgetService {
+	return NewBudgetServiceWithEndpoint("https://management.azure.com")
+}
+
+// NewBudgetServiceWithEndpoint returns a budget service which makes requests to the given Resource Manager endpoint
+// rather than the public Azure endpoint.
+func NewBudgetServiceWithEndpoint(endpoint string) BudgetService {
+	return BudgetService{
+		azureService:    newAzureService(),
+		apiVersion:      "2023-05-01",
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		managementScope: "https://management.azure.com/.default",
+	}
+}
+
+// ListSubscriptionBudgets returns the cost budgets defined for the subscription, and for each of the provided
+// resource groups in the subscription.
+func (bs *BudgetService) ListSubscriptionBudgets(subscriptionId string, resourceGroups []model.ResourceGroup) ([]model.AzureBudget, error) {
+	budgets, err := bs.ListBudgets(subscriptionId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rg := range resourceGroups {
+		rgBudgets, err := bs.ListBudgets(subscriptionId, rg.Name)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, rgBudgets...)
+	}
+
+	return budgets, nil
+}
+
+// ListBudgets returns the cost budgets defined at the scope of the subscription, or of a resource group within the
+// subscription if a resource group name is provided. Usage budgets are not included.
+func (bs *BudgetService) ListBudgets(subscriptionId string, resourceGroup string) ([]model.AzureBudget, error) {
+	scope := fmt.Sprintf("/subscriptions/%s", subscriptionId)
+	if len(resourceGroup) > 0 {
+		scope = fmt.Sprintf("%s/resourceGroups/%s", scope, url.PathEscape(resourceGroup))
+	}
+
+	requestUrl := fmt.Sprintf("%s%s/providers/Microsoft.Consumption/budgets?api-version=%s", bs.endpoint, scope, bs.apiVersion)
+
+	token, err := bs.getAccessToken(bs.managementScope)
+	if err != nil {
+		return nil, err
+	}
+
+	var budgets []model.AzureBudget
+
+	for len(requestUrl) > 0 {
+		req, err := http.NewRequest(http.MethodGet, requestUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := makeRequest(req, nil, 3)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list budgets for %s: %s", scope, err.Error())
+		}
+
+		var budgetResp budgetResponse
+		err = json.NewDecoder(resp.Body).Decode(&budgetResp)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range budgetResp.Value {
+			if !strings.EqualFold(b.Properties.Category, "Cost") {
+				continue
+			}
+
+			budgets = append(budgets, model.AzureBudget{
+				Id:             b.Id,
+				Name:           b.Name,
+				SubscriptionId: subscriptionId,
+				ResourceGroup:  resourceGroup,
+				Amount:         b.Properties.Amount,
+				TimeGrain:      b.Properties.TimeGrain,
+				StartDate:      b.Properties.TimePeriod.StartDate,
+				EndDate:        b.Properties.TimePeriod.EndDate,
+			})
+		}
+
+		requestUrl = budgetResp.NextLink
+	}
+
+	return budgets, nil
+}
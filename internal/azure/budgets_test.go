@@ -0,0 +1,223 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/dazfuller/azcosts/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "test-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// budgetJson returns a budget in the form returned by the Consumption Budgets API.
+func budgetJson(name string, category string, amount float64, timeGrain string) string {
+	return fmt.Sprintf(`{
+		"id": "/budgets/%[1]s",
+		"name": "%[1]s",
+		"properties": {
+			"category": "%[2]s",
+			"amount": %[3]g,
+			"timeGrain": "%[4]s",
+			"timePeriod": {"startDate": "2024-01-01T00:00:00Z", "endDate": "2024-12-31T00:00:00Z"}
+		}
+	}`, name, category, amount, timeGrain)
+}
+
+func newTestBudgetService(t *testing.T, handler http.HandlerFunc) BudgetService {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("api-version") != "2023-05-01" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	previous := credential
+	credential = fakeCredential{}
+	t.Cleanup(func() { credential = previous })
+
+	return NewBudgetServiceWithEndpoint(server.URL + "/")
+}
+
+func TestListBudgets(t *testing.T) {
+	const subscriptionId = "00000000-0000-0000-0000-000000000001"
+
+	tests := []struct {
+		name          string
+		resourceGroup string
+		responses     map[string]string
+		want          []model.AzureBudget
+	}{
+		{
+			name: "subscription scope",
+			responses: map[string]string{
+				"/subscriptions/" + subscriptionId + "/providers/Microsoft.Consumption/budgets": `{"value": [` +
+					budgetJson("monthly", "Cost", 100, "Monthly") + `,` +
+					budgetJson("usage", "Usage", 5, "Monthly") + `]}`,
+			},
+			want: []model.AzureBudget{
+				{
+					Id:             "/budgets/monthly",
+					Name:           "monthly",
+					SubscriptionId: subscriptionId,
+					Amount:         100,
+					TimeGrain:      "Monthly",
+					StartDate:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+					EndDate:        time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name:          "resource group scope",
+			resourceGroup: "rg web",
+			responses: map[string]string{
+				"/subscriptions/" + subscriptionId + "/resourceGroups/rg web/providers/Microsoft.Consumption/budgets": `{"value": [` +
+					budgetJson("quarterly", "Cost", 300, "Quarterly") + `]}`,
+			},
+			want: []model.AzureBudget{
+				{
+					Id:             "/budgets/quarterly",
+					Name:           "quarterly",
+					SubscriptionId: subscriptionId,
+					ResourceGroup:  "rg web",
+					Amount:         300,
+					TimeGrain:      "Quarterly",
+					StartDate:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+					EndDate:        time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "paged results",
+			responses: map[string]string{
+				"/subscriptions/" + subscriptionId + "/providers/Microsoft.Consumption/budgets": `{"value": [` +
+					budgetJson("first", "Cost", 1, "Monthly") + `], "nextLink": "{server}/next?api-version=2023-05-01"}`,
+				"/next": `{"value": [` + budgetJson("second", "Cost", 2, "Annually") + `]}`,
+			},
+			want: []model.AzureBudget{
+				{Name: "first", Amount: 1, TimeGrain: "Monthly"},
+				{Name: "second", Amount: 2, TimeGrain: "Annually"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var serverUrl string
+			bs := newTestBudgetService(t, func(w http.ResponseWriter, r *http.Request) {
+				response, ok := tt.responses[r.URL.Path]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write([]byte(strings.ReplaceAll(response, "{server}", serverUrl)))
+			})
+			serverUrl = bs.endpoint
+
+			budgets, err := bs.ListBudgets(subscriptionId, tt.resourceGroup)
+			if err != nil {
+				t.Fatalf("ListBudgets() error = %v", err)
+			}
+
+			if len(budgets) != len(tt.want) {
+				t.Fatalf("ListBudgets() returned %d budgets, want %d", len(budgets), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := budgets[i]
+				if got.Name != want.Name || got.Amount != want.Amount || got.TimeGrain != want.TimeGrain {
+					t.Errorf("ListBudgets() budget %d = %+v, want %+v", i, got, want)
+				}
+				if len(want.Id) > 0 && got != want {
+					t.Errorf("ListBudgets() budget %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestListBudgetsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{name: "not found", status: http.StatusNotFound, wantErr: "404 Not Found"},
+		{name: "forbidden", status: http.StatusForbidden, wantErr: "403 Forbidden"},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: "500 Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs := newTestBudgetService(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{"error": {"code": "Test"}}`))
+			})
+
+			_, err := bs.ListBudgets("sub", "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "/subscriptions/sub") {
+				t.Errorf("ListBudgets() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListBudgetsRetriesThrottledRequests(t *testing.T) {
+	attempts := 0
+	bs := newTestBudgetService(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"value": [` + budgetJson("monthly", "Cost", 100, "Monthly") + `]}`))
+	})
+
+	budgets, err := bs.ListBudgets("sub", "")
+	if err != nil {
+		t.Fatalf("ListBudgets() error = %v", err)
+	}
+	if attempts != 2 || len(budgets) != 1 {
+		t.Errorf("ListBudgets() made %d attempts and returned %d budgets, want 2 and 1", attempts, len(budgets))
+	}
+}
+
+func TestListSubscriptionBudgets(t *testing.T) {
+	bs := newTestBudgetService(t, func(w http.ResponseWriter, r *http.Request) {
+		name := "subscription"
+		if _, rg, ok := strings.Cut(r.URL.Path, "/resourceGroups/"); ok {
+			name, _, _ = strings.Cut(rg, "/")
+		}
+		_, _ = w.Write([]byte(`{"value": [` + budgetJson(name, "Cost", 10, "Monthly") + `]}`))
+	})
+
+	budgets, err := bs.ListSubscriptionBudgets("sub", []model.ResourceGroup{{Name: "rg-a"}, {Name: "rg-b"}})
+	if err != nil {
+		t.Fatalf("ListSubscriptionBudgets() error = %v", err)
+	}
+
+	var got []string
+	for _, b := range budgets {
+		got = append(got, b.Name+":"+b.ResourceGroup)
+	}
+	if want := "subscription:,rg-a:rg-a,rg-b:rg-b"; strings.Join(got, ",") != want {
+		t.Errorf("ListSubscriptionBudgets() = %s, want %s", strings.Join(got, ","), want)
+	}
+}
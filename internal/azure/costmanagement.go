@@ -44,6 +44,24 @@ type costManagementRequest struct {
 	DataSet    dataset    `json:"dataSet"`
 }
 
+type forecastAggregation struct {
+	TotalCost aggregationFunction `json:"totalCost"`
+}
+
+type forecastDataset struct {
+	Granularity string              `json:"granularity"`
+	Aggregation forecastAggregation `json:"aggregation"`
+}
+
+type forecastRequest struct {
+	Type                    string          `json:"type"`
+	TimeFrame               string          `json:"timeframe"`
+	TimePeriod              timePeriod      `json:"timePeriod"`
+	DataSet                 forecastDataset `json:"dataset"`
+	IncludeActualCost       bool            `json:"includeActualCost"`
+	IncludeFreshPartialCost bool            `json:"includeFreshPartialCost"`
+}
+
 type costResponse struct {
 	Id         string `json:"id"`
 	Name       string `json:"name"`
@@ -63,21 +81,50 @@ type costResponse struct {
 
 type CostService struct {
 	azureService
-	apiVersion      string
-	endpoint        string
-	managementScope string
+	apiVersion       string
+	endpoint         string
+	forecastEndpoint string
+	managementScope  string
 }
 
 func NewCostService() CostService {
 	return CostService{
-		azureService:    newAzureService(),
-		apiVersion:      "2023-11-01",
-		endpoint:        "https://management.azure.com/subscriptions/%s/providers/Microsoft.CostManagement/query",
-		managementScope: "https://management.azure.com/.default",
+		azureService:     newAzureService(),
+		apiVersion:       "2023-11-01",
+		endpoint:         "https://management.azure.com%s/providers/Microsoft.CostManagement/query",
+		forecastEndpoint: "https://management.azure.com%s/providers/Microsoft.CostManagement/forecast",
+		managementScope:  "https://management.azure.com/.default",
 	}
 }
 
+// SubscriptionScope returns the Cost Management scope for a subscription.
+func SubscriptionScope(subscriptionId string) string {
+	return fmt.Sprintf("/subscriptions/%s", subscriptionId)
+}
+
+// ManagementGroupScope returns the Cost Management scope for a management group.
+func ManagementGroupScope(managementGroupId string) string {
+	return fmt.Sprintf("/providers/Microsoft.Management/managementGroups/%s", managementGroupId)
+}
+
+// BillingAccountScope returns the Cost Management scope for a billing account, such as an EA enrollment or an MCA
+// billing account.
+func BillingAccountScope(billingAccountId string) string {
+	return fmt.Sprintf("/providers/Microsoft.Billing/billingAccounts/%s", billingAccountId)
+}
+
+// BillingProfileScope returns the Cost Management scope for a billing profile of an MCA billing account.
+func BillingProfileScope(billingAccountId string, billingProfileId string) string {
+	return fmt.Sprintf("%s/billingProfiles/%s", BillingAccountScope(billingAccountId), billingProfileId)
+}
+
 func (svc *CostService) ResourceGroupCostsForPeriod(subscriptionId string, year int, month int) ([]model.ResourceGroupCost, error) {
+	return svc.ScopeCostsForPeriod(SubscriptionScope(subscriptionId), year, month)
+}
+
+// ScopeCostsForPeriod returns the costs of every resource group within the scope for the billing period, where the
+// scope may contain multiple subscriptions.
+func (svc *CostService) ScopeCostsForPeriod(scope string, year int, month int) ([]model.ResourceGroupCost, error) {
 	currentTime := time.Now().UTC()
 
 	// Validate that the year is not in the future
@@ -143,7 +190,108 @@ func (svc *CostService) ResourceGroupCostsForPeriod(subscriptionId string, year
 		return nil, fmt.Errorf("unable to marshal request data: %s", err.Error())
 	}
 
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf(svc.endpoint, subscriptionId), nil)
+	log.Printf("Requesing billing information for scope %s, billing period %s", scope, billingFrom.Format("2006-01"))
+
+	var costs []model.ResourceGroupCost
+	requestUrl := fmt.Sprintf(svc.endpoint, scope)
+
+	for len(requestUrl) > 0 {
+		req, err := http.NewRequest(http.MethodPost, requestUrl, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create request: %s", err.Error())
+		}
+		q := req.URL.Query()
+		if !q.Has("api-version") {
+			q.Add("api-version", svc.apiVersion)
+			req.URL.RawQuery = q.Encode()
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("ClientType", "CostManagementAppV1")
+
+		resp, err := makeRequest(req, requestContent, 3)
+		if err != nil {
+			return nil, err
+		}
+
+		responseVal := costResponse{}
+		decoder := json.NewDecoder(resp.Body)
+		err = decoder.Decode(&responseVal)
+		resp.Body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode response: %s", err.Error())
+		}
+
+		columns := make(map[string]int)
+
+		for i, v := range responseVal.Properties.Columns {
+			columns[v.Name] = i
+		}
+
+		for _, r := range responseVal.Properties.Rows {
+			costs = append(costs, model.ResourceGroupCost{
+				SubscriptionId:   stringValue(r[columns["SubscriptionId"]]),
+				SubscriptionName: stringValue(r[columns["SubscriptionName"]]),
+				Name:             stringValue(r[columns["ResourceGroupName"]]),
+				BillingPeriod:    billingFrom,
+				Cost:             r[columns["Cost"]].(float64),
+				CostUSD:          r[columns["CostUSD"]].(float64),
+				Currency:         stringValue(r[columns["Currency"]]),
+				Scope:            scope,
+			})
+		}
+
+		requestUrl, _ = responseVal.Properties.NextLink.(string)
+	}
+
+	return costs, nil
+}
+
+// SubscriptionForecast returns the Cost Management forecast of the total cost of the subscription for each month,
+// starting with the current month, over the given number of months. The forecast for the current month includes the
+// actual costs incurred to date.
+func (svc *CostService) SubscriptionForecast(subscriptionId string, months int) ([]model.CostForecast, error) {
+	if months < 1 {
+		return nil, fmt.Errorf("invalid number of months")
+	}
+
+	currentTime := time.Now().UTC()
+	forecastFrom := time.Date(currentTime.Year(), currentTime.Month(), 1, 0, 0, 0, 0, time.UTC)
+	forecastTo := forecastFrom.AddDate(0, months, 0).Add(time.Second * -1)
+
+	token, err := svc.getAccessToken(svc.managementScope)
+	if err != nil {
+		return nil, fmt.Errorf("unable to acquire token: %s", err.Error())
+	}
+
+	requestData := forecastRequest{
+		Type:      "ActualCost",
+		TimeFrame: "Custom",
+		TimePeriod: timePeriod{
+			From: forecastFrom,
+			To:   forecastTo,
+		},
+		DataSet: forecastDataset{
+			Granularity: "Daily",
+			Aggregation: forecastAggregation{
+				TotalCost: aggregationFunction{
+					Name:     "Cost",
+					Function: "Sum",
+				},
+			},
+		},
+		IncludeActualCost:       true,
+		IncludeFreshPartialCost: false,
+	}
+
+	requestContent, err := json.Marshal(requestData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal request data: %s", err.Error())
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf(svc.forecastEndpoint, SubscriptionScope(subscriptionId)), nil)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create request: %s", err.Error())
 	}
@@ -155,7 +303,7 @@ func (svc *CostService) ResourceGroupCostsForPeriod(subscriptionId string, year
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("ClientType", "CostManagementAppV1")
 
-	log.Printf("Requesing billing information for subscription %s, billing period %s", subscriptionId, billingFrom.Format("2006-01"))
+	log.Printf("Requesting forecast for subscription %s, from %s to %s", subscriptionId, forecastFrom.Format("2006-01"), forecastTo.Format("2006-01"))
 
 	resp, err := makeRequest(req, requestContent, 3)
 	if err != nil {
@@ -177,21 +325,61 @@ func (svc *CostService) ResourceGroupCostsForPeriod(subscriptionId string, year
 		columns[v.Name] = i
 	}
 
-	costs := make([]model.ResourceGroupCost, len(responseVal.Properties.Rows))
+	for _, name := range []string{"UsageDate", "Cost", "Currency"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("forecast response does not contain the %s column", name)
+		}
+	}
+
+	forecasts := make([]model.CostForecast, months)
+	for i := range forecasts {
+		forecasts[i] = model.CostForecast{
+			SubscriptionId: subscriptionId,
+			BillingPeriod:  forecastFrom.AddDate(0, i, 0),
+		}
+	}
+
+	for _, r := range responseVal.Properties.Rows {
+		if len(r) < len(responseVal.Properties.Columns) {
+			return nil, fmt.Errorf("forecast response contains a row with %d of %d columns", len(r), len(responseVal.Properties.Columns))
+		}
+
+		date, ok := r[columns["UsageDate"]].(float64)
+		if !ok {
+			return nil, fmt.Errorf("unable to parse forecast date: unexpected value %v", r[columns["UsageDate"]])
+		}
+
+		usageDate, err := time.Parse("20060102", fmt.Sprintf("%.0f", date))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse forecast date: %s", err.Error())
+		}
+
+		cost, ok := r[columns["Cost"]].(float64)
+		if !ok {
+			return nil, fmt.Errorf("unable to parse forecast cost for %s: unexpected value %v", usageDate.Format("2006-01-02"), r[columns["Cost"]])
+		}
+
+		i := (usageDate.Year()-forecastFrom.Year())*12 + int(usageDate.Month()) - int(forecastFrom.Month())
+		if i < 0 || i >= months {
+			continue
+		}
 
-	for i, r := range responseVal.Properties.Rows {
-		costs[i] = model.ResourceGroupCost{
-			SubscriptionId:   r[columns["SubscriptionId"]].(string),
-			SubscriptionName: r[columns["SubscriptionName"]].(string),
-			Name:             r[columns["ResourceGroupName"]].(string),
-			BillingPeriod:    billingFrom,
-			Cost:             r[columns["Cost"]].(float64),
-			CostUSD:          r[columns["CostUSD"]].(float64),
-			Currency:         r[columns["Currency"]].(string),
+		forecasts[i].Cost += cost
+		if currency := stringValue(r[columns["Currency"]]); len(currency) > 0 {
+			forecasts[i].Currency = currency
 		}
 	}
 
-	return costs, nil
+	return forecasts, nil
+}
+
+// stringValue returns the value of a response column as a string, where columns without a value, such as charges
+// which are not associated with a resource group, are returned as an empty string.
+func stringValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return ""
 }
 
 func makeRequest(req *http.Request, content []byte, retryLimit int) (*http.Response, error) {
@@ -202,7 +390,9 @@ func makeRequest(req *http.Request, content []byte, retryLimit int) (*http.Respo
 		log.Printf("Making request, attempt %d", attempt)
 
 		attemptReq := req.Clone(req.Context())
-		attemptReq.Body = io.NopCloser(bytes.NewBuffer(content))
+		if content != nil {
+			attemptReq.Body = io.NopCloser(bytes.NewBuffer(content))
+		}
 
 		resp, err := client.Do(attemptReq)
 		if err != nil {
@@ -213,7 +403,11 @@ func makeRequest(req *http.Request, content []byte, retryLimit int) (*http.Respo
 		if resp.StatusCode == http.StatusOK {
 			return resp, nil
 		} else if resp.StatusCode == 429 {
+			resp.Body.Close()
 			retryAfter := resp.Header.Get("X-Ms-Ratelimit-Microsoft.costmanagement-Entity-Retry-After")
+			if len(retryAfter) == 0 {
+				retryAfter = resp.Header.Get("Retry-After")
+			}
 			if len(retryAfter) == 0 {
 				retryAfter = "40"
 			}
@@ -236,5 +430,5 @@ func makeRequest(req *http.Request, content []byte, retryLimit int) (*http.Respo
 		attempt++
 	}
 
-	return nil, fmt.Errorf("unable to successfully query cost management api after %d attempt(s)", retryLimit)
+	return nil, fmt.Errorf("unable to successfully complete request after %d attempt(s)", retryLimit)
 }
@@ -0,0 +1,117 @@
+package azure
+
+import (
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"os"
+)
+
+const (
+	DefaultAuth          = "default"
+	CliAuth              = "cli"
+	ServicePrincipalAuth = "service-principal"
+	ManagedIdentityAuth  = "managed-identity"
+	DeviceCodeAuth       = "device-code"
+	WorkloadIdentityAuth = "workload-identity"
+)
+
+// AuthMethods lists the supported authentication methods.
+var AuthMethods = []string{DefaultAuth, CliAuth, ServicePrincipalAuth, ManagedIdentityAuth, DeviceCodeAuth, WorkloadIdentityAuth}
+
+// CredentialOptions determines how the services authenticate with Azure.
+type CredentialOptions struct {
+	// Method is one of the AuthMethods, an empty value uses the DefaultAzureCredential.
+	Method string
+	// TenantId is the tenant to authenticate in. Where not provided the tenant is determined by the method, such as
+	// the AZURE_TENANT_ID environment variable or the current Azure CLI account.
+	TenantId string
+	// ClientId is the application id of the service principal, the client id of a user-assigned managed identity, or
+	// the application to use for device code authentication.
+	ClientId string
+	// CertificatePath is the path to a PEM or PKCS12 certificate used to authenticate a service principal instead of
+	// a client secret.
+	CertificatePath string
+}
+
+// UseCredential creates the credential described by the options and uses it for every service created afterwards.
+// The credential is created once so that interactive methods, such as device code, only prompt the user once.
+func UseCredential(options CredentialOptions) error {
+	cred, err := newCredential(options)
+	if err != nil {
+		return fmt.Errorf("unable to create the %s credential: %s", options.Method, err.Error())
+	}
+
+	credential = cred
+	return nil
+}
+
+func newCredential(options CredentialOptions) (azcore.TokenCredential, error) {
+	switch options.Method {
+	case DefaultAuth, "":
+		return azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{TenantID: options.TenantId})
+	case CliAuth:
+		return azidentity.NewAzureCLICredential(&azidentity.AzureCLICredentialOptions{TenantID: options.TenantId})
+	case ServicePrincipalAuth:
+		return newServicePrincipalCredential(options)
+	case ManagedIdentityAuth:
+		miOptions := azidentity.ManagedIdentityCredentialOptions{}
+		if len(options.ClientId) > 0 {
+			miOptions.ID = azidentity.ClientID(options.ClientId)
+		}
+		return azidentity.NewManagedIdentityCredential(&miOptions)
+	case DeviceCodeAuth:
+		return azidentity.NewDeviceCodeCredential(&azidentity.DeviceCodeCredentialOptions{
+			TenantID: options.TenantId,
+			ClientID: options.ClientId,
+		})
+	case WorkloadIdentityAuth:
+		return azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			TenantID: options.TenantId,
+			ClientID: options.ClientId,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported authentication method '%s'", options.Method)
+	}
+}
+
+// newServicePrincipalCredential creates a credential for a service principal using either a certificate or a client
+// secret. Values not provided in the options are read from the same environment variables used by the
+// EnvironmentCredential, so that secrets do not need to be passed on the command line.
+func newServicePrincipalCredential(options CredentialOptions) (azcore.TokenCredential, error) {
+	tenantId := valueOrEnv(options.TenantId, "AZURE_TENANT_ID")
+	clientId := valueOrEnv(options.ClientId, "AZURE_CLIENT_ID")
+	certificatePath := valueOrEnv(options.CertificatePath, "AZURE_CLIENT_CERTIFICATE_PATH")
+
+	if len(tenantId) == 0 || len(clientId) == 0 {
+		return nil, fmt.Errorf("a tenant and client id must be provided, either as arguments or using AZURE_TENANT_ID and AZURE_CLIENT_ID")
+	}
+
+	if len(certificatePath) > 0 {
+		data, err := os.ReadFile(certificatePath)
+		if err != nil {
+			return nil, err
+		}
+
+		certs, key, err := azidentity.ParseCertificates(data, []byte(os.Getenv("AZURE_CLIENT_CERTIFICATE_PASSWORD")))
+		if err != nil {
+			return nil, err
+		}
+
+		return azidentity.NewClientCertificateCredential(tenantId, clientId, certs, key, nil)
+	}
+
+	secret := os.Getenv("AZURE_CLIENT_SECRET")
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("either a certificate or the AZURE_CLIENT_SECRET environment variable must be provided")
+	}
+
+	return azidentity.NewClientSecretCredential(tenantId, clientId, secret, nil)
+}
+
+func valueOrEnv(value string, name string) string {
+	if len(value) > 0 {
+		return value
+	}
+	return os.Getenv(name)
+}
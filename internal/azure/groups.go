@@ -58,6 +58,10 @@ func (rgs *ResourceGroupService) ListResourceGroups(subscriptionId string) ([]mo
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to list resource groups for subscription %s: %s", subscriptionId, resp.Status)
+	}
+
 	var resGroupResp resourceGroupResponse
 	err = json.NewDecoder(resp.Body).Decode(&resGroupResp)
 	if err != nil {
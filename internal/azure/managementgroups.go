@@ -0,0 +1,118 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type managementGroupChild struct {
+	Id          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Type        string                 `json:"type"`
+	DisplayName string                 `json:"displayName"`
+	Children    []managementGroupChild `json:"children"`
+}
+
+type managementGroupResponse struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Properties struct {
+		TenantId    string `json:"tenantId"`
+		DisplayName string `json:"displayName"`
+		Details     struct {
+			Parent struct {
+				Id          string `json:"id"`
+				Name        string `json:"name"`
+				DisplayName string `json:"displayName"`
+			} `json:"parent"`
+		} `json:"details"`
+		Children []managementGroupChild `json:"children"`
+	} `json:"properties"`
+}
+
+type ManagementGroupService struct {
+	azureService
+	apiVersion      string
+	endpoint        string
+	managementScope string
+}
+
+func NewManagementGroupService() ManagementGroupService {
+	return ManagementGroupService{
+		azureService:    newAzureService(),
+		apiVersion:      "2020-05-01",
+		endpoint:        "https://management.azure.com/providers/Microsoft.Management/managementGroups",
+		managementScope: "https://management.azure.com/.default",
+	}
+}
+
+// GetHierarchy returns the management group and all of its descendant management groups and subscriptions, each
+// with the id of its parent management group.
+func (mgs *ManagementGroupService) GetHierarchy(managementGroupId string) ([]model.ManagementGroupNode, error) {
+	url := fmt.Sprintf("%s/%s?api-version=%s&$expand=children&$recurse=true", mgs.endpoint, managementGroupId, mgs.apiVersion)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := mgs.getAccessToken(mgs.managementScope)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	client := http.Client{Timeout: time.Second * 30}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respContent, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unable to get management group %s. %s: %s", managementGroupId, resp.Status, respContent)
+	}
+
+	var mgResp managementGroupResponse
+	err = json.NewDecoder(resp.Body).Decode(&mgResp)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []model.ManagementGroupNode{
+		{
+			Id:          mgResp.Name,
+			DisplayName: mgResp.Properties.DisplayName,
+			ParentId:    mgResp.Properties.Details.Parent.Name,
+			Type:        model.ManagementGroupType,
+		},
+	}
+
+	return appendManagementGroupChildren(nodes, mgResp.Name, mgResp.Properties.Children), nil
+}
+
+func appendManagementGroupChildren(nodes []model.ManagementGroupNode, parentId string, children []managementGroupChild) []model.ManagementGroupNode {
+	for _, child := range children {
+		nodeType := model.ManagementGroupType
+		if strings.EqualFold(child.Type, "/subscriptions") {
+			nodeType = model.SubscriptionType
+		}
+
+		nodes = append(nodes, model.ManagementGroupNode{
+			Id:          child.Name,
+			DisplayName: child.DisplayName,
+			ParentId:    parentId,
+			Type:        nodeType,
+		})
+
+		nodes = appendManagementGroupChildren(nodes, child.Name, child.Children)
+	}
+
+	return nodes
+}
@@ -8,18 +8,25 @@ import (
 	"log"
 )
 
+// credential is the credential shared by every service, set using UseCredential. When it has not been set the
+// DefaultAzureCredential is used.
+var credential azcore.TokenCredential
+
 type azureService struct {
 	identity azcore.TokenCredential
 }
 
 func newAzureService() azureService {
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
-	if err != nil {
-		log.Fatal(err)
+	if credential == nil {
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		credential = cred
 	}
 
 	return azureService{
-		identity: cred,
+		identity: credential,
 	}
 }
 
@@ -0,0 +1,327 @@
+package browse
+
+import (
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"golang.org/x/term"
+	"os"
+	"slices"
+	"strings"
+)
+
+type view int
+
+const (
+	resourceGroupView view = iota
+	subscriptionView
+)
+
+type sortColumn int
+
+const (
+	sortByName sortColumn = iota
+	sortByParent
+	sortByPeriod
+	sortByTotal
+)
+
+var sortNames = map[sortColumn]string{
+	sortByName:   "name",
+	sortByParent: "parent",
+	sortByPeriod: "period",
+	sortByTotal:  "total",
+}
+
+// row is a single row of either view, holding the name and costs of a resource group or subscription.
+type row struct {
+	name      string
+	parent    string
+	active    bool
+	costs     []model.BillingPeriodCost
+	totalCost float64
+}
+
+// browser holds the state of the terminal UI.
+type browser struct {
+	labels        Labels
+	resourceRows  []row
+	subscriptions []row
+	periods       []model.BillingPeriodCost
+
+	view       view
+	sortBy     sortColumn
+	descending bool
+	filter     string
+	editing    bool
+	parent     string
+	period     int
+	offset     int
+	cursor     int
+
+	width  int
+	height int
+}
+
+// Labels are the names of the levels being browsed, such as "Resource Group" and "Subscription".
+type Labels struct {
+	Name   string
+	Parent string
+}
+
+// Run shows the costs in a full-screen terminal UI until the user quits. Standard input and output must be a terminal.
+func Run(costs []model.ResourceGroupSummary, labels Labels) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("browse must be run in an interactive terminal")
+	}
+
+	if len(costs) == 0 {
+		return fmt.Errorf("there are no costs to browse")
+	}
+
+	b := newBrowser(costs, labels)
+
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(os.Stdin.Fd()), state)
+
+	// Use the alternate screen so that the terminal is restored to its previous content on exit.
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan key)
+	go readKeys(os.Stdin, keys)
+
+	resized := make(chan struct{}, 1)
+	stop := notifyResize(resized)
+	defer stop()
+
+	for {
+		b.width, b.height, err = term.GetSize(int(os.Stdout.Fd()))
+		if err != nil {
+			return err
+		}
+
+		if err = b.render(os.Stdout); err != nil {
+			return err
+		}
+
+		select {
+		case k, ok := <-keys:
+			if !ok || !b.handleKey(k) {
+				return nil
+			}
+		case <-resized:
+		}
+	}
+}
+
+func newBrowser(costs []model.ResourceGroupSummary, labels Labels) *browser {
+	b := &browser{
+		labels:     labels,
+		periods:    costs[0].Costs,
+		sortBy:     sortByTotal,
+		descending: true,
+	}
+
+	subscriptions := make(map[string]*row)
+	for _, rg := range costs {
+		b.resourceRows = append(b.resourceRows, row{
+			name:      rg.Name,
+			parent:    rg.SubscriptionName,
+			active:    rg.Active,
+			costs:     rg.Costs,
+			totalCost: rg.TotalCost,
+		})
+
+		sub, ok := subscriptions[rg.SubscriptionName]
+		if !ok {
+			sub = &row{name: rg.SubscriptionName, active: true, costs: make([]model.BillingPeriodCost, len(rg.Costs))}
+			for i, bp := range rg.Costs {
+				sub.costs[i] = model.BillingPeriodCost{Period: bp.Period, Forecast: bp.Forecast, Source: bp.Source}
+			}
+			subscriptions[rg.SubscriptionName] = sub
+		}
+
+		for i, bp := range rg.Costs {
+			sub.costs[i].Total += bp.Total
+		}
+		sub.totalCost += rg.TotalCost
+	}
+
+	for _, sub := range subscriptions {
+		b.subscriptions = append(b.subscriptions, *sub)
+	}
+
+	// Start on the latest billing period which is not a forecast.
+	for i, bp := range b.periods {
+		if !bp.Forecast {
+			b.period = i
+		}
+	}
+
+	return b
+}
+
+// rows returns the rows of the current view, filtered and sorted.
+func (b *browser) rows() []row {
+	source := b.resourceRows
+	if b.view == subscriptionView {
+		source = b.subscriptions
+	}
+
+	filter := strings.ToLower(b.filter)
+	rows := make([]row, 0, len(source))
+	for _, r := range source {
+		if b.view == resourceGroupView && len(b.parent) > 0 && r.parent != b.parent {
+			continue
+		}
+		if len(filter) > 0 && !strings.Contains(strings.ToLower(r.name), filter) && !strings.Contains(strings.ToLower(r.parent), filter) {
+			continue
+		}
+		rows = append(rows, r)
+	}
+
+	slices.SortStableFunc(rows, func(x, y row) int {
+		var c int
+		switch b.sortBy {
+		case sortByName:
+			c = strings.Compare(strings.ToLower(x.name), strings.ToLower(y.name))
+		case sortByParent:
+			c = strings.Compare(strings.ToLower(x.parent), strings.ToLower(y.parent))
+		case sortByPeriod:
+			c = compareFloat(x.costs[b.period].Total, y.costs[b.period].Total)
+		case sortByTotal:
+			c = compareFloat(x.totalCost, y.totalCost)
+		}
+		if c == 0 {
+			c = strings.Compare(strings.ToLower(x.name), strings.ToLower(y.name))
+		} else if b.descending {
+			c = -c
+		}
+		return c
+	})
+
+	return rows
+}
+
+func compareFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// handleKey updates the state for the key pressed, returning false when the user has asked to quit.
+func (b *browser) handleKey(k key) bool {
+	if b.editing {
+		switch k.code {
+		case keyEnter:
+			b.editing = false
+		case keyEscape:
+			b.editing = false
+			b.filter = ""
+		case keyBackspace:
+			if r := []rune(b.filter); len(r) > 0 {
+				b.filter = string(r[:len(r)-1])
+			}
+		case keyRune:
+			b.filter += string(k.r)
+		}
+		b.cursor, b.offset = 0, 0
+		return true
+	}
+
+	rows := b.rows()
+	pageSize := max(b.tableHeight(), 1)
+
+	switch k.code {
+	case keyCtrlC:
+		return false
+	case keyUp:
+		b.cursor--
+	case keyDown:
+		b.cursor++
+	case keyPageUp:
+		b.cursor -= pageSize
+	case keyPageDown:
+		b.cursor += pageSize
+	case keyHome:
+		b.cursor = 0
+	case keyEnd:
+		b.cursor = len(rows) - 1
+	case keyLeft:
+		b.period = max(b.period-1, 0)
+	case keyRight:
+		b.period = min(b.period+1, len(b.periods)-1)
+	case keyTab:
+		b.toggleView()
+	case keyEnter:
+		if b.view == subscriptionView && b.cursor < len(rows) {
+			b.parent = rows[b.cursor].name
+			b.filter = ""
+			b.view = resourceGroupView
+			b.cursor, b.offset = 0, 0
+		}
+	case keyEscape, keyBackspace:
+		if len(b.filter) > 0 {
+			b.filter = ""
+		} else if len(b.parent) > 0 {
+			b.parent = ""
+			b.view = subscriptionView
+		}
+		b.cursor, b.offset = 0, 0
+	case keyRune:
+		switch k.r {
+		case 'q', 'Q':
+			return false
+		case 'k':
+			b.cursor--
+		case 'j':
+			b.cursor++
+		case 'h':
+			b.period = max(b.period-1, 0)
+		case 'l':
+			b.period = min(b.period+1, len(b.periods)-1)
+		case '/':
+			b.editing = true
+		case 's':
+			b.sortBy = (b.sortBy + 1) % sortColumn(len(sortNames))
+		case 'r':
+			b.descending = !b.descending
+		case 'n':
+			b.setSort(sortByName)
+		case 'p':
+			b.setSort(sortByPeriod)
+		case 't':
+			b.setSort(sortByTotal)
+		}
+	}
+
+	b.cursor = min(max(b.cursor, 0), max(len(b.rows())-1, 0))
+	return true
+}
+
+// setSort sorts by the column, or reverses the order when already sorted by it.
+func (b *browser) setSort(column sortColumn) {
+	if b.sortBy == column {
+		b.descending = !b.descending
+		return
+	}
+	b.sortBy = column
+	b.descending = column == sortByPeriod || column == sortByTotal
+}
+
+func (b *browser) toggleView() {
+	if b.view == resourceGroupView {
+		b.view = subscriptionView
+	} else {
+		b.view = resourceGroupView
+	}
+	b.parent = ""
+	b.cursor, b.offset = 0, 0
+}
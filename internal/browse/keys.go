@@ -0,0 +1,114 @@
+package browse
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyTab
+	keyEscape
+	keyBackspace
+	keyCtrlC
+	keyUnknown
+)
+
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// escapeSequences maps the escape sequences sent by terminals for the special keys, without the leading escape.
+var escapeSequences = map[string]keyCode{
+	"[A":  keyUp,
+	"[B":  keyDown,
+	"[C":  keyRight,
+	"[D":  keyLeft,
+	"OA":  keyUp,
+	"OB":  keyDown,
+	"OC":  keyRight,
+	"OD":  keyLeft,
+	"[5~": keyPageUp,
+	"[6~": keyPageDown,
+	"[H":  keyHome,
+	"[F":  keyEnd,
+	"OH":  keyHome,
+	"OF":  keyEnd,
+	"[1~": keyHome,
+	"[4~": keyEnd,
+}
+
+// readKeys reads the keys pressed from the terminal in raw mode, closing the channel when the input ends. Escape
+// sequences are expected to arrive in a single read, so that an escape on its own is treated as the escape key.
+func readKeys(r io.Reader, keys chan<- key) {
+	defer close(keys)
+
+	buffer := make([]byte, 64)
+	for {
+		n, err := r.Read(buffer)
+		if err != nil {
+			return
+		}
+
+		for _, k := range parseKeys(buffer[:n]) {
+			keys <- k
+		}
+	}
+}
+
+func parseKeys(input []byte) []key {
+	var keys []key
+
+	for len(input) > 0 {
+		switch b := input[0]; {
+		case b == 0x1b && len(input) == 1:
+			keys = append(keys, key{code: keyEscape})
+			input = input[1:]
+		case b == 0x1b:
+			code, length := keyUnknown, 1
+			for sequence, c := range escapeSequences {
+				if len(input) > len(sequence) && string(input[1:len(sequence)+1]) == sequence {
+					code, length = c, len(sequence)+1
+					break
+				}
+			}
+			if code == keyUnknown {
+				code, length = keyEscape, 1
+			}
+			keys = append(keys, key{code: code})
+			input = input[length:]
+		case b == '\r' || b == '\n':
+			keys = append(keys, key{code: keyEnter})
+			input = input[1:]
+		case b == '\t':
+			keys = append(keys, key{code: keyTab})
+			input = input[1:]
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, key{code: keyBackspace})
+			input = input[1:]
+		case b == 0x03:
+			keys = append(keys, key{code: keyCtrlC})
+			input = input[1:]
+		case b < 0x20:
+			input = input[1:]
+		default:
+			r, size := utf8.DecodeRune(input)
+			keys = append(keys, key{code: keyRune, r: r})
+			input = input[size:]
+		}
+	}
+
+	return keys
+}
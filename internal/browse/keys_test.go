@@ -0,0 +1,37 @@
+package browse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []key
+	}{
+		{name: "runes", input: "q/é", want: []key{{code: keyRune, r: 'q'}, {code: keyRune, r: '/'}, {code: keyRune, r: 'é'}}},
+		{name: "arrow keys", input: "\x1b[A\x1b[B\x1b[C\x1b[D", want: []key{{code: keyUp}, {code: keyDown}, {code: keyRight}, {code: keyLeft}}},
+		{name: "application mode arrow keys", input: "\x1bOA\x1bOD", want: []key{{code: keyUp}, {code: keyLeft}}},
+		{name: "paging keys", input: "\x1b[5~\x1b[6~", want: []key{{code: keyPageUp}, {code: keyPageDown}}},
+		{name: "home and end", input: "\x1b[H\x1b[F\x1bOH\x1bOF\x1b[1~\x1b[4~", want: []key{{code: keyHome}, {code: keyEnd}, {code: keyHome}, {code: keyEnd}, {code: keyHome}, {code: keyEnd}}},
+		{name: "escape on its own", input: "\x1b", want: []key{{code: keyEscape}}},
+		{name: "escape followed by a rune", input: "\x1bq", want: []key{{code: keyEscape}, {code: keyRune, r: 'q'}}},
+		{name: "unknown escape sequence", input: "\x1b[Z", want: []key{{code: keyEscape}, {code: keyRune, r: '['}, {code: keyRune, r: 'Z'}}},
+		{name: "enter", input: "\r\n", want: []key{{code: keyEnter}, {code: keyEnter}}},
+		{name: "tab", input: "\t", want: []key{{code: keyTab}}},
+		{name: "backspace", input: "\x7f\x08", want: []key{{code: keyBackspace}, {code: keyBackspace}}},
+		{name: "ctrl-c", input: "\x03", want: []key{{code: keyCtrlC}}},
+		{name: "other control characters are ignored", input: "\x01a\x1f", want: []key{{code: keyRune, r: 'a'}}},
+		{name: "no input", input: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseKeys([]byte(tt.input)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseKeys(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
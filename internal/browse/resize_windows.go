@@ -0,0 +1,7 @@
+package browse
+
+// notifyResize does nothing on Windows, which does not signal when the console is resized, and so the new size is
+// picked up on the next key press.
+func notifyResize(chan<- struct{}) func() {
+	return func() {}
+}
@@ -0,0 +1,235 @@
+package browse
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/formats"
+	"github.com/dazfuller/azcosts/internal/model"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	costWidth      = 12
+	activeWidth    = 6
+	minNameWidth   = 20
+	minParentWidth = 15
+	// chromeHeight is the number of lines used by the title, table header, totals, and status lines.
+	chromeHeight = 5
+)
+
+const (
+	reverse = "\x1b[7m"
+	bold    = "\x1b[1m"
+	dim     = "\x1b[2m"
+	reset   = "\x1b[0m"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// layout is the width of each column, and the billing periods which fit on screen.
+type layout struct {
+	name        int
+	parent      int
+	trend       int
+	firstPeriod int
+	lastPeriod  int
+}
+
+func (b *browser) tableHeight() int {
+	return b.height - chromeHeight
+}
+
+func (b *browser) layout(rows []row) layout {
+	l := layout{trend: max(len(b.periods), 5)}
+
+	fixed := l.trend + 1 + costWidth + 1
+	minimum := minNameWidth + 1
+	if b.view == resourceGroupView {
+		fixed += activeWidth + 1
+		minimum += minParentWidth + 1
+	}
+
+	visible := min(len(b.periods), max((b.width-fixed-minimum)/(costWidth+1), 1))
+
+	// Keep the selected billing period on screen, preferring to show the periods before it.
+	l.lastPeriod = max(b.period, visible-1)
+	l.firstPeriod = l.lastPeriod - visible + 1
+
+	remaining := b.width - fixed - visible*(costWidth+1)
+
+	nameLength, parentLength := utf8.RuneCountInString(b.nameLabel()), utf8.RuneCountInString(b.labels.Parent)
+	for _, r := range rows {
+		nameLength = max(nameLength, utf8.RuneCountInString(r.name))
+		parentLength = max(parentLength, utf8.RuneCountInString(r.parent))
+	}
+
+	if b.view == resourceGroupView {
+		remaining -= 2
+		l.parent = max(min(parentLength, remaining*2/5), minParentWidth)
+		l.name = max(min(nameLength, remaining-l.parent), minNameWidth)
+		l.parent = max(min(parentLength, remaining-l.name), minParentWidth)
+	} else {
+		l.name = max(min(nameLength, remaining-1), minNameWidth)
+	}
+
+	return l
+}
+
+func (b *browser) nameLabel() string {
+	if b.view == subscriptionView {
+		return b.labels.Parent
+	}
+	return b.labels.Name
+}
+
+func (b *browser) render(out io.Writer) error {
+	rows := b.rows()
+	l := b.layout(rows)
+
+	height := max(b.tableHeight(), 1)
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	} else if b.cursor >= b.offset+height {
+		b.offset = b.cursor - height + 1
+	}
+
+	w := bufio.NewWriter(out)
+	w.WriteString("\x1b[H\x1b[2J")
+
+	title := fmt.Sprintf("%s%s costs%s", bold, b.nameLabel(), reset)
+	if len(b.parent) > 0 {
+		title += fmt.Sprintf(" in %s", b.parent)
+	}
+	if b.editing || len(b.filter) > 0 {
+		title += fmt.Sprintf("  filter: %s", b.filter)
+		if b.editing {
+			title += "_"
+		}
+	}
+	direction := "ascending"
+	if b.descending {
+		direction = "descending"
+	}
+	title += fmt.Sprintf("  %s(%d rows, sorted by %s %s)%s", dim, len(rows), sortNames[b.sortBy], direction, reset)
+	w.WriteString(title + "\r\n")
+
+	// Header
+	header := pad(b.nameLabel(), l.name) + " "
+	if b.view == resourceGroupView {
+		header += pad(b.labels.Parent, l.parent) + " " + pad("Active", activeWidth) + " "
+	}
+	header += pad("Trend", l.trend)
+	w.WriteString(header)
+	for i := l.firstPeriod; i <= l.lastPeriod; i++ {
+		label := fmt.Sprintf(" %*s", costWidth, formats.PeriodLabel(b.periods[i]))
+		if i == b.period {
+			label = " " + reverse + label[1:] + reset
+		}
+		w.WriteString(label)
+	}
+	w.WriteString(fmt.Sprintf(" %*s\r\n", costWidth, "Total"))
+
+	separator := strings.Repeat("─", l.name) + " "
+	if b.view == resourceGroupView {
+		separator += strings.Repeat("─", l.parent) + " " + strings.Repeat("─", activeWidth) + " "
+	}
+	separator += strings.Repeat("─", l.trend)
+	for i := l.firstPeriod; i <= l.lastPeriod; i++ {
+		separator += " " + strings.Repeat("─", costWidth)
+	}
+	separator += " " + strings.Repeat("─", costWidth)
+	w.WriteString(separator + "\r\n")
+
+	// Rows
+	for i := b.offset; i < len(rows) && i < b.offset+height; i++ {
+		r := rows[i]
+
+		line := pad(r.name, l.name) + " "
+		if b.view == resourceGroupView {
+			line += pad(r.parent, l.parent) + " " + pad(fmt.Sprint(r.active), activeWidth) + " "
+		}
+		line += pad(sparkline(r.costs), l.trend)
+		for p := l.firstPeriod; p <= l.lastPeriod; p++ {
+			line += fmt.Sprintf(" %*.2f", costWidth, r.costs[p].Total)
+		}
+		line += fmt.Sprintf(" %*.2f", costWidth, r.totalCost)
+
+		switch {
+		case i == b.cursor:
+			line = reverse + line + reset
+		case b.view == resourceGroupView && !r.active:
+			line = dim + line + reset
+		}
+		w.WriteString(line + "\r\n")
+	}
+
+	for i := len(rows) - b.offset; i < height; i++ {
+		w.WriteString("\r\n")
+	}
+
+	// Totals of the rows shown
+	totals := pad("Total", l.name) + " "
+	if b.view == resourceGroupView {
+		totals += pad("", l.parent) + " " + pad("", activeWidth) + " "
+	}
+	totals += pad("", l.trend)
+	grandTotal := 0.0
+	for _, r := range rows {
+		grandTotal += r.totalCost
+	}
+	for p := l.firstPeriod; p <= l.lastPeriod; p++ {
+		periodTotal := 0.0
+		for _, r := range rows {
+			periodTotal += r.costs[p].Total
+		}
+		totals += fmt.Sprintf(" %*.2f", costWidth, periodTotal)
+	}
+	totals += fmt.Sprintf(" %*.2f", costWidth, grandTotal)
+	w.WriteString(bold + totals + reset + "\r\n")
+
+	help := fmt.Sprintf("↑↓ move  ←→ period  tab %ss  / filter  s sort  r reverse  q quit", strings.ToLower(b.labels.Parent))
+	if b.view == subscriptionView {
+		help = fmt.Sprintf("↑↓ move  ←→ period  enter open  tab %ss  / filter  s sort  r reverse  q quit", strings.ToLower(b.labels.Name))
+	} else if len(b.parent) > 0 {
+		help = "↑↓ move  ←→ period  esc back  / filter  s sort  r reverse  q quit"
+	}
+	w.WriteString(dim + truncate(help, b.width) + reset)
+
+	return w.Flush()
+}
+
+// pad truncates or pads the value with spaces to the given width, marking truncated values with an ellipsis.
+func pad(value string, width int) string {
+	value = truncate(value, width)
+	return value + strings.Repeat(" ", max(width-utf8.RuneCountInString(value), 0))
+}
+
+func truncate(value string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(value) > width {
+		return string([]rune(value)[:width-1]) + "…"
+	}
+	return value
+}
+
+// sparkline returns a bar for each billing period, scaled to the highest cost of the row.
+func sparkline(costs []model.BillingPeriodCost) string {
+	maxCost := 0.0
+	for _, bp := range costs {
+		maxCost = max(maxCost, bp.Total)
+	}
+
+	s := strings.Builder{}
+	for _, bp := range costs {
+		i := 0
+		if maxCost > 0 && bp.Total > 0 {
+			i = int(bp.Total / maxCost * float64(len(sparkBlocks)-1))
+		}
+		s.WriteRune(sparkBlocks[i])
+	}
+	return s.String()
+}
@@ -0,0 +1,185 @@
+package browse
+
+import (
+	"bytes"
+	"github.com/dazfuller/azcosts/internal/model"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var escapeCodes = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+func testBrowser() *browser {
+	costs := []model.ResourceGroupSummary{
+		{
+			Name:             "rg-web",
+			SubscriptionName: "Production",
+			Active:           true,
+			TotalCost:        60,
+			Costs:            []model.BillingPeriodCost{{Period: "2024-01", Total: 10}, {Period: "2024-02", Total: 20}, {Period: "2024-03", Total: 30, Forecast: true}},
+		},
+		{
+			Name:             "rg-data-with-a-very-long-name",
+			SubscriptionName: "Production",
+			TotalCost:        6,
+			Costs:            []model.BillingPeriodCost{{Period: "2024-01", Total: 4}, {Period: "2024-02", Total: 2}, {Period: "2024-03", Forecast: true}},
+		},
+		{
+			Name:             "rg-test",
+			SubscriptionName: "Development",
+			Active:           true,
+			TotalCost:        3,
+			Costs:            []model.BillingPeriodCost{{Period: "2024-01", Total: 1}, {Period: "2024-02", Total: 1}, {Period: "2024-03", Total: 1, Forecast: true}},
+		},
+	}
+
+	return newBrowser(costs, Labels{Name: "Resource Group", Parent: "Subscription"})
+}
+
+// renderLines renders the browser and returns each line of the screen without any escape codes.
+func renderLines(t *testing.T, b *browser) []string {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := b.render(&out); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(escapeCodes.ReplaceAllString(out.String(), ""), "\r\n")
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(b *browser)
+		want  []string
+	}{
+		{
+			name: "resource groups",
+			want: []string{
+				"Resource Group costs  (3 rows, sorted by total descending)",
+				"Resource Group           Subscription    Active Trend      2024-02        Total",
+				"──────────────────────── ─────────────── ────── ───── ──────────── ────────────",
+				"rg-web                   Production      true   ▃▅█          20.00        60.00",
+				"rg-data-with-a-very-lon… Production      false  █▄▁           2.00         6.00",
+				"rg-test                  Development     true   ███           1.00         3.00",
+				"",
+				"Total                                                        23.00        69.00",
+				"↑↓ move  ←→ period  tab subscriptions  / filter  s sort  r reverse  q quit",
+			},
+		},
+		{
+			name: "subscriptions",
+			setup: func(b *browser) {
+				b.view = subscriptionView
+			},
+			want: []string{
+				"Subscription costs  (2 rows, sorted by total descending)",
+				"Subscription         Trend      2024-01      2024-02  2024-03 (F)        Total",
+				"──────────────────── ───── ──────────── ──────────── ──────────── ────────────",
+				"Production           ▄▆█          14.00        22.00        30.00        66.00",
+				"Development          ███           1.00         1.00         1.00         3.00",
+				"",
+				"",
+				"Total                             15.00        23.00        31.00        69.00",
+				"↑↓ move  ←→ period  enter open  tab resource groups  / filter  s sort  r revers…",
+			},
+		},
+		{
+			name: "filtered resource groups of a subscription",
+			setup: func(b *browser) {
+				b.parent = "Production"
+				b.filter = "web"
+				b.sortBy, b.descending = sortByName, false
+			},
+			want: []string{
+				"Resource Group costs in Production  filter: web  (1 rows, sorted by name ascending)",
+				"Resource Group       Subscription    Active Trend      2024-02        Total",
+				"──────────────────── ─────────────── ────── ───── ──────────── ────────────",
+				"rg-web               Production      true   ▃▅█          20.00        60.00",
+				"",
+				"",
+				"",
+				"Total                                                    20.00        60.00",
+				"↑↓ move  ←→ period  esc back  / filter  s sort  r reverse  q quit",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := testBrowser()
+			b.width, b.height = 80, 9
+			if tt.setup != nil {
+				tt.setup(b)
+			}
+
+			if got := renderLines(t, b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("render() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestRenderFitsWidth(t *testing.T) {
+	for width := 80; width <= 140; width++ {
+		for _, v := range []view{resourceGroupView, subscriptionView} {
+			b := testBrowser()
+			b.width, b.height, b.view = width, 9, v
+
+			for i, line := range renderLines(t, b) {
+				if length := utf8.RuneCountInString(line); length > width {
+					t.Errorf("render() line %d at width %d is %d characters: %q", i, width, length, line)
+				}
+			}
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		value string
+		width int
+		want  string
+	}{
+		{value: "rg", width: 4, want: "rg  "},
+		{value: "rg-web", width: 6, want: "rg-web"},
+		{value: "rg-web", width: 4, want: "rg-…"},
+		{value: "café-rg", width: 5, want: "café…"},
+		{value: "rg", width: 0, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := pad(tt.value, tt.width); got != tt.want {
+				t.Errorf("pad(%q, %d) = %q, want %q", tt.value, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		totals []float64
+		want   string
+	}{
+		{name: "rising", totals: []float64{0, 25, 50, 100}, want: "▁▂▄█"},
+		{name: "no costs", totals: []float64{0, 0}, want: "▁▁"},
+		{name: "credits", totals: []float64{-10, 10}, want: "▁█"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			costs := make([]model.BillingPeriodCost, len(tt.totals))
+			for i, total := range tt.totals {
+				costs[i].Total = total
+			}
+			if got := sparkline(costs); got != tt.want {
+				t.Errorf("sparkline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package budgets
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type budgetFile struct {
+	Budgets []struct {
+		Name          string  `yaml:"name"`
+		Subscription  string  `yaml:"subscription"`
+		ResourceGroup string  `yaml:"resourceGroup"`
+		Amount        float64 `yaml:"amount"`
+		Threshold     float64 `yaml:"threshold"`
+		StartPeriod   string  `yaml:"startPeriod"`
+		EndPeriod     string  `yaml:"endPeriod"`
+	} `yaml:"budgets"`
+}
+
+// Load reads the budget definitions from a YAML or CSV file, the format being determined by the file extension.
+//
+// Subscription and resource group values are case-insensitive glob patterns, where an empty value matches
+// everything. A threshold of 0 means the budget is considered breached once utilisation reaches 100%.
+func Load(budgetPath string) ([]model.Budget, error) {
+	file, err := os.Open(budgetPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var budgets []model.Budget
+
+	switch strings.ToLower(filepath.Ext(budgetPath)) {
+	case ".yaml", ".yml":
+		budgets, err = readYaml(file)
+	case ".csv":
+		budgets, err = readCsv(file)
+	default:
+		return nil, fmt.Errorf("unsupported budget file type, expected a .yaml, .yml, or .csv file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read budget file: %s", err.Error())
+	}
+
+	for i := range budgets {
+		if err := validateBudget(&budgets[i]); err != nil {
+			return nil, fmt.Errorf("invalid budget %d: %s", i+1, err.Error())
+		}
+	}
+
+	return budgets, nil
+}
+
+func readYaml(reader io.Reader) ([]model.Budget, error) {
+	var content budgetFile
+	if err := yaml.NewDecoder(reader).Decode(&content); err != nil {
+		return nil, err
+	}
+
+	budgets := make([]model.Budget, len(content.Budgets))
+	for i, b := range content.Budgets {
+		budgets[i] = model.Budget{
+			Name:          b.Name,
+			Subscription:  b.Subscription,
+			ResourceGroup: b.ResourceGroup,
+			Amount:        b.Amount,
+			Threshold:     b.Threshold,
+			StartPeriod:   b.StartPeriod,
+			EndPeriod:     b.EndPeriod,
+		}
+	}
+
+	return budgets, nil
+}
+
+func readCsv(reader io.Reader) ([]model.Budget, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("file does not contain a header row")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[normalizeColumnName(name)] = i
+	}
+
+	if _, ok := columns["amount"]; !ok {
+		return nil, fmt.Errorf("file does not contain an amount column")
+	}
+
+	value := func(record []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	budgets := make([]model.Budget, 0, len(records)-1)
+	for i, record := range records[1:] {
+		budget := model.Budget{
+			Name:          value(record, "name"),
+			Subscription:  value(record, "subscription"),
+			ResourceGroup: value(record, "resourcegroup"),
+			StartPeriod:   value(record, "startperiod"),
+			EndPeriod:     value(record, "endperiod"),
+		}
+
+		budget.Amount, err = strconv.ParseFloat(value(record, "amount"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount on line %d", i+2)
+		}
+
+		if threshold := value(record, "threshold"); len(threshold) > 0 {
+			budget.Threshold, err = strconv.ParseFloat(threshold, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid threshold on line %d", i+2)
+			}
+		}
+
+		budgets = append(budgets, budget)
+	}
+
+	return budgets, nil
+}
+
+func normalizeColumnName(name string) string {
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+func validateBudget(budget *model.Budget) error {
+	if budget.Amount <= 0 {
+		return fmt.Errorf("amount must be greater than 0")
+	}
+
+	if budget.Threshold < 0 {
+		return fmt.Errorf("threshold cannot be negative")
+	}
+
+	for _, period := range []string{budget.StartPeriod, budget.EndPeriod} {
+		if _, err := time.Parse("2006-01", period); len(period) > 0 && err != nil {
+			return fmt.Errorf("invalid period '%s', periods must be in the format YYYY-MM", period)
+		}
+	}
+
+	for _, pattern := range []string{budget.Subscription, budget.ResourceGroup} {
+		if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s'", pattern)
+		}
+	}
+
+	if len(budget.Name) == 0 {
+		budget.Name = budget.Subscription
+		if len(budget.ResourceGroup) > 0 {
+			budget.Name = fmt.Sprintf("%s/%s", budget.Subscription, budget.ResourceGroup)
+		}
+		if len(budget.Name) == 0 {
+			budget.Name = "All subscriptions"
+		}
+	}
+
+	return nil
+}
+
+// Evaluate compares each budget against the costs of the matching resource groups for every billing period, returning
+// the utilisation of each budget per period. Forecast periods are not evaluated.
+//
+// The subscription pattern is matched against both the subscription name and id. Budgets without a threshold use
+// the default threshold, which is a percentage of the budget amount.
+func Evaluate(budgets []model.Budget, costs []model.ResourceGroupSummary, defaultThreshold float64) []model.BudgetUtilisation {
+	utilisation := make([]model.BudgetUtilisation, 0)
+
+	for _, budget := range budgets {
+		threshold := budget.Threshold
+		if threshold == 0 {
+			threshold = defaultThreshold
+		}
+
+		actuals := make(map[string]float64)
+		var periods []string
+
+		for _, rg := range costs {
+			if len(periods) == 0 {
+				for _, bp := range rg.Costs {
+					if !bp.Forecast {
+						periods = append(periods, bp.Period)
+					}
+				}
+			}
+
+			if !matches(budget.Subscription, rg.SubscriptionName, rg.SubscriptionId) || !matches(budget.ResourceGroup, rg.Name) {
+				continue
+			}
+
+			for _, bp := range rg.Costs {
+				if !bp.Forecast {
+					actuals[bp.Period] += bp.Total
+				}
+			}
+		}
+
+		for _, period := range periods {
+			if (len(budget.StartPeriod) > 0 && period < budget.StartPeriod) || (len(budget.EndPeriod) > 0 && period > budget.EndPeriod) {
+				continue
+			}
+
+			actual := actuals[period]
+			entry := model.BudgetUtilisation{
+				Name:          budget.Name,
+				Subscription:  budget.Subscription,
+				ResourceGroup: budget.ResourceGroup,
+				Period:        period,
+				Amount:        budget.Amount,
+				Actual:        actual,
+				Utilisation:   actual / budget.Amount * 100,
+				Threshold:     threshold,
+			}
+
+			if actual > budget.Amount {
+				entry.Overrun = actual - budget.Amount
+			}
+			entry.Breached = entry.Utilisation >= threshold
+
+			utilisation = append(utilisation, entry)
+		}
+	}
+
+	return utilisation
+}
+
+// FromAzure converts budgets collected from Azure into budget definitions which can be evaluated. Budget amounts are
+// converted to a monthly amount based on the budget time grain, and the budget only applies to the billing periods
+// between its start and end dates.
+func FromAzure(azureBudgets []model.AzureBudget) []model.Budget {
+	budgets := make([]model.Budget, 0, len(azureBudgets))
+
+	for _, ab := range azureBudgets {
+		amount := ab.Amount
+		switch strings.ToLower(ab.TimeGrain) {
+		case "quarterly", "billingquarter":
+			amount /= 3
+		case "annually", "billingannual":
+			amount /= 12
+		}
+
+		budget := model.Budget{
+			Name:          ab.Name,
+			Subscription:  ab.SubscriptionId,
+			ResourceGroup: ab.ResourceGroup,
+			Amount:        amount,
+		}
+
+		if !ab.StartDate.IsZero() {
+			budget.StartPeriod = ab.StartDate.Format("2006-01")
+		}
+		if !ab.EndDate.IsZero() {
+			budget.EndPeriod = ab.EndDate.Format("2006-01")
+		}
+
+		budgets = append(budgets, budget)
+	}
+
+	return budgets
+}
+
+// matches returns true if the pattern is empty or matches any of the values, ignoring case.
+func matches(pattern string, values ...string) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+
+	pattern = strings.ToLower(pattern)
+	for _, value := range values {
+		if ok, _ := path.Match(pattern, strings.ToLower(value)); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,302 @@
+package budgets
+
+import (
+	"github.com/dazfuller/azcosts/internal/model"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeBudgetFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	budgetPath := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(budgetPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return budgetPath
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []model.Budget
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			file: "budgets.yaml",
+			content: `budgets:
+  - name: Production
+    subscription: prod-*
+    amount: 1000
+    threshold: 80
+    startPeriod: "2024-01"
+  - subscription: dev
+    resourceGroup: rg-web
+    amount: 50
+`,
+			want: []model.Budget{
+				{Name: "Production", Subscription: "prod-*", Amount: 1000, Threshold: 80, StartPeriod: "2024-01"},
+				{Name: "dev/rg-web", Subscription: "dev", ResourceGroup: "rg-web", Amount: 50},
+			},
+		},
+		{
+			name: "csv with normalised column names",
+			file: "budgets.CSV",
+			content: `Name,Subscription,Resource Group,Amount,threshold,end_period
+,,,250.5,,2024-06
+Shared,*,rg-shared,10,90,
+`,
+			want: []model.Budget{
+				{Name: "All subscriptions", Amount: 250.5, EndPeriod: "2024-06"},
+				{Name: "Shared", Subscription: "*", ResourceGroup: "rg-shared", Amount: 10, Threshold: 90},
+			},
+		},
+		{
+			name:    "unsupported extension",
+			file:    "budgets.json",
+			content: `{}`,
+			wantErr: "unsupported budget file type",
+		},
+		{
+			name:    "csv without amount column",
+			file:    "budgets.csv",
+			content: "name,subscription\nTest,sub\n",
+			wantErr: "does not contain an amount column",
+		},
+		{
+			name:    "csv with invalid amount",
+			file:    "budgets.csv",
+			content: "name,amount\nTest,lots\n",
+			wantErr: "invalid amount on line 2",
+		},
+		{
+			name:    "amount must be positive",
+			file:    "budgets.yml",
+			content: "budgets:\n  - name: Test\n    amount: 0\n",
+			wantErr: "invalid budget 1: amount must be greater than 0",
+		},
+		{
+			name:    "invalid period",
+			file:    "budgets.yml",
+			content: "budgets:\n  - amount: 10\n    startPeriod: 2024-13\n",
+			wantErr: "invalid period '2024-13'",
+		},
+		{
+			name:    "invalid pattern",
+			file:    "budgets.yml",
+			content: "budgets:\n  - amount: 10\n    subscription: \"prod-[\"\n",
+			wantErr: "invalid pattern 'prod-['",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budgets, err := Load(writeBudgetFile(t, tt.file, tt.content))
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !reflect.DeepEqual(budgets, tt.want) {
+				t.Errorf("Load() = %+v, want %+v", budgets, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	costs := []model.ResourceGroupSummary{
+		{
+			Name:             "rg-web",
+			SubscriptionId:   "00000000-0000-0000-0000-000000000001",
+			SubscriptionName: "Production",
+			Costs: []model.BillingPeriodCost{
+				{Period: "2024-01", Total: 60},
+				{Period: "2024-02", Total: 90},
+				{Period: "2024-03", Total: 500, Forecast: true},
+			},
+		},
+		{
+			Name:             "rg-data",
+			SubscriptionId:   "00000000-0000-0000-0000-000000000001",
+			SubscriptionName: "Production",
+			Costs: []model.BillingPeriodCost{
+				{Period: "2024-01", Total: 20},
+				{Period: "2024-02", Total: 40},
+				{Period: "2024-03", Total: 500, Forecast: true},
+			},
+		},
+		{
+			Name:             "rg-web",
+			SubscriptionId:   "00000000-0000-0000-0000-000000000002",
+			SubscriptionName: "Development",
+			Costs: []model.BillingPeriodCost{
+				{Period: "2024-01", Total: 5},
+				{Period: "2024-02", Total: 15},
+				{Period: "2024-03", Total: 500, Forecast: true},
+			},
+		},
+	}
+
+	type result struct {
+		period   string
+		actual   float64
+		breached bool
+	}
+
+	tests := []struct {
+		name   string
+		budget model.Budget
+		want   []result
+	}{
+		{
+			name:   "all costs",
+			budget: model.Budget{Amount: 100},
+			want:   []result{{"2024-01", 85, false}, {"2024-02", 145, true}},
+		},
+		{
+			name:   "subscription name glob ignoring case",
+			budget: model.Budget{Subscription: "PROD*", Amount: 100},
+			want:   []result{{"2024-01", 80, false}, {"2024-02", 130, true}},
+		},
+		{
+			name:   "subscription id and resource group",
+			budget: model.Budget{Subscription: "*-000000000002", ResourceGroup: "rg-web", Amount: 10},
+			want:   []result{{"2024-01", 5, false}, {"2024-02", 15, true}},
+		},
+		{
+			name:   "budget threshold",
+			budget: model.Budget{ResourceGroup: "rg-data", Amount: 50, Threshold: 40},
+			want:   []result{{"2024-01", 20, true}, {"2024-02", 40, true}},
+		},
+		{
+			name:   "period range",
+			budget: model.Budget{Amount: 100, StartPeriod: "2024-02", EndPeriod: "2024-02"},
+			want:   []result{{"2024-02", 145, true}},
+		},
+		{
+			name:   "no matching resource groups",
+			budget: model.Budget{Subscription: "Staging", Amount: 100},
+			want:   []result{{"2024-01", 0, false}, {"2024-02", 0, false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			utilisation := Evaluate([]model.Budget{tt.budget}, costs, 100)
+
+			if len(utilisation) != len(tt.want) {
+				t.Fatalf("Evaluate() returned %d periods, want %d", len(utilisation), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := utilisation[i]
+				if got.Period != want.period || math.Abs(got.Actual-want.actual) > 1e-9 || got.Breached != want.breached {
+					t.Errorf("Evaluate() period %d = %+v, want %+v", i, got, want)
+				}
+				if overrun := math.Max(want.actual-tt.budget.Amount, 0); math.Abs(got.Overrun-overrun) > 1e-9 {
+					t.Errorf("Evaluate() overrun = %v, want %v", got.Overrun, overrun)
+				}
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		values  []string
+		want    bool
+	}{
+		{pattern: "", values: []string{"anything"}, want: true},
+		{pattern: "prod", values: []string{"Prod"}, want: true},
+		{pattern: "prod-*", values: []string{"PROD-WEST"}, want: true},
+		{pattern: "rg-?", values: []string{"rg-1"}, want: true},
+		{pattern: "rg-?", values: []string{"rg-10"}, want: false},
+		{pattern: "prod", values: []string{"dev", "prod"}, want: true},
+		{pattern: "prod", values: []string{"production"}, want: false},
+		{pattern: "prod", values: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if got := matches(tt.pattern, tt.values...); got != tt.want {
+				t.Errorf("matches(%q, %v) = %v, want %v", tt.pattern, tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromAzure(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget model.AzureBudget
+		want   model.Budget
+	}{
+		{
+			name:   "monthly",
+			budget: model.AzureBudget{Name: "monthly", SubscriptionId: "sub", Amount: 100, TimeGrain: "Monthly"},
+			want:   model.Budget{Name: "monthly", Subscription: "sub", Amount: 100},
+		},
+		{
+			name:   "quarterly",
+			budget: model.AzureBudget{Name: "quarterly", SubscriptionId: "sub", Amount: 300, TimeGrain: "Quarterly"},
+			want:   model.Budget{Name: "quarterly", Subscription: "sub", Amount: 100},
+		},
+		{
+			name:   "billing quarter",
+			budget: model.AzureBudget{Name: "quarter", SubscriptionId: "sub", Amount: 90, TimeGrain: "BillingQuarter"},
+			want:   model.Budget{Name: "quarter", Subscription: "sub", Amount: 30},
+		},
+		{
+			name:   "annually",
+			budget: model.AzureBudget{Name: "annual", SubscriptionId: "sub", Amount: 1200, TimeGrain: "Annually"},
+			want:   model.Budget{Name: "annual", Subscription: "sub", Amount: 100},
+		},
+		{
+			name:   "billing annual",
+			budget: model.AzureBudget{Name: "annual", SubscriptionId: "sub", Amount: 240, TimeGrain: "billingannual"},
+			want:   model.Budget{Name: "annual", Subscription: "sub", Amount: 20},
+		},
+		{
+			name: "resource group with dates",
+			budget: model.AzureBudget{
+				Name:           "rg",
+				SubscriptionId: "sub",
+				ResourceGroup:  "rg-web",
+				Amount:         50,
+				TimeGrain:      "Monthly",
+				StartDate:      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				EndDate:        time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC),
+			},
+			want: model.Budget{
+				Name:          "rg",
+				Subscription:  "sub",
+				ResourceGroup: "rg-web",
+				Amount:        50,
+				StartPeriod:   "2024-03",
+				EndPeriod:     "2025-02",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromAzure([]model.AzureBudget{tt.budget})
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("FromAzure() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
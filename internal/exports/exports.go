@@ -0,0 +1,362 @@
+package exports
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BilledCost    = "billed"
+	EffectiveCost = "effective"
+)
+
+// Options determines how the costs in FOCUS files are read, and has no effect on Cost Management export files.
+type Options struct {
+	// CostType is either BilledCost or EffectiveCost, an empty value uses BilledCost.
+	CostType string
+	// ResourceGroupTag is the name of a tag to use in place of the resource group, for data from providers which do
+	// not have resource groups. Where a row does not have the tag the resource group is used instead.
+	ResourceGroupTag string
+}
+
+// columnNames lists the names used for each value across the actual and amortized cost export schemas of EA, MCA,
+// and pay-as-you-go accounts. Names are normalized before being compared.
+var columnNames = map[string][]string{
+	"subscriptionId":   {"subscriptionid", "subscriptionguid"},
+	"subscriptionName": {"subscriptionname"},
+	"resourceGroup":    {"resourcegroup", "resourcegroupname"},
+	"cost":             {"costinbillingcurrency", "pretaxcost", "cost"},
+	"costUSD":          {"costinusd"},
+	"currency":         {"billingcurrencycode", "billingcurrency", "currency"},
+	"billingPeriod":    {"billingperiodstartdate"},
+	"date":             {"date", "usagedatetime"},
+}
+
+var dateLayouts = []string{
+	"01/02/2006",
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"01/02/2006 15:04:05",
+}
+
+type costKey struct {
+	subscriptionId string
+	billingPeriod  time.Time
+	resourceGroup  string
+}
+
+// rowMapper maps a single row of a file into a resource group cost, returning false where the row should be skipped.
+// Values are looked up using the names of the columns in the columnNames of the file schema.
+type rowMapper func(value func(column string) string) (model.ResourceGroupCost, bool, error)
+
+type aggregator struct {
+	costs map[costKey]*model.ResourceGroupCost
+	keys  []costKey
+}
+
+func (a *aggregator) add(cost model.ResourceGroupCost) {
+	key := costKey{cost.SubscriptionId, cost.BillingPeriod, strings.ToLower(cost.Name)}
+
+	existing, ok := a.costs[key]
+	if !ok {
+		a.costs[key] = &cost
+		a.keys = append(a.keys, key)
+		return
+	}
+
+	existing.Cost += cost.Cost
+	existing.CostUSD += cost.CostUSD
+	if len(existing.SubscriptionName) == 0 {
+		existing.SubscriptionName = cost.SubscriptionName
+	}
+}
+
+func (a *aggregator) result() []model.ResourceGroupCost {
+	slices.SortFunc(a.keys, func(x, y costKey) int {
+		if c := strings.Compare(x.subscriptionId, y.subscriptionId); c != 0 {
+			return c
+		}
+		if c := x.billingPeriod.Compare(y.billingPeriod); c != 0 {
+			return c
+		}
+		return strings.Compare(x.resourceGroup, y.resourceGroup)
+	})
+
+	aggregated := make([]model.ResourceGroupCost, len(a.keys))
+	for i, key := range a.keys {
+		aggregated[i] = *a.costs[key]
+	}
+
+	return aggregated
+}
+
+// Load reads the Cost Management export or FOCUS files at the given paths, aggregating the costs into a single cost per
+// resource group for each subscription and billing period. Paths can be files or directories, where directories are
+// searched for .csv, .csv.gz, and .parquet files, including in any subdirectories created by scheduled exports.
+//
+// The schema of each CSV file is detected from its header, while Parquet files are always read as FOCUS files.
+func Load(paths []string, options Options) ([]model.ResourceGroupCost, error) {
+	if len(options.CostType) == 0 {
+		options.CostType = BilledCost
+	} else if options.CostType != BilledCost && options.CostType != EffectiveCost {
+		return nil, fmt.Errorf("unsupported cost type '%s'", options.CostType)
+	}
+
+	files, err := findExportFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no export files found in the provided paths")
+	}
+
+	costs := aggregator{costs: make(map[costKey]*model.ResourceGroupCost)}
+
+	for _, file := range files {
+		if isParquetFile(file) {
+			err = readParquetFile(file, options, costs.add)
+		} else {
+			err = readCsvFile(file, options, costs.add)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read export file %s: %s", file, err.Error())
+		}
+	}
+
+	return costs.result(), nil
+}
+
+func findExportFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && (isCsvFile(path) || isParquetFile(path)) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+func isCsvFile(path string) bool {
+	name := strings.ToLower(path)
+	return strings.HasSuffix(name, ".csv") || strings.HasSuffix(name, ".csv.gz")
+}
+
+func isParquetFile(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".parquet")
+}
+
+func readCsvFile(path string, options Options, add func(model.ResourceGroupCost)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	return readCsv(reader, options, add)
+}
+
+func readCsv(reader io.Reader, options Options, add func(model.ResourceGroupCost)) error {
+	csvReader := csv.NewReader(reader)
+	csvReader.ReuseRecord = true
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("file does not contain a header row")
+	} else if err != nil {
+		return err
+	}
+
+	var columns map[string]int
+	var mapRow rowMapper
+
+	if isFocusHeader(header) {
+		columns = mapColumns(header, focusColumnNames)
+		mapRow, err = focusRowMapper(columns, options)
+	} else {
+		columns = mapColumns(header, columnNames)
+		mapRow, err = exportRowMapper(columns)
+	}
+	if err != nil {
+		return err
+	}
+
+	line := 1
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		line++
+
+		cost, ok, err := mapRow(func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		})
+		if err != nil {
+			return fmt.Errorf("%s on line %d", err.Error(), line)
+		} else if ok {
+			add(cost)
+		}
+	}
+
+	return nil
+}
+
+// exportRowMapper returns the mapper for the rows of a Cost Management export file.
+func exportRowMapper(columns map[string]int) (rowMapper, error) {
+	for _, required := range []string{"subscriptionId", "resourceGroup", "cost"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("file does not contain a %s column", required)
+		}
+	}
+	if _, ok := columns["billingPeriod"]; !ok {
+		if _, ok := columns["date"]; !ok {
+			return nil, fmt.Errorf("file does not contain a billing period or date column")
+		}
+	}
+
+	return func(value func(string) string) (model.ResourceGroupCost, bool, error) {
+		subscriptionId := strings.ToLower(value("subscriptionId"))
+		if len(subscriptionId) == 0 {
+			return model.ResourceGroupCost{}, false, nil
+		}
+
+		billingPeriod, err := parseBillingPeriod(value("billingPeriod"), value("date"))
+		if err != nil {
+			return model.ResourceGroupCost{}, false, fmt.Errorf("invalid date")
+		}
+
+		cost, err := parseAmount(value("cost"))
+		if err != nil {
+			return model.ResourceGroupCost{}, false, fmt.Errorf("invalid cost")
+		}
+
+		currency := value("currency")
+
+		costUSD, err := usdAmount(value("costUSD"), cost, currency)
+		if err != nil {
+			return model.ResourceGroupCost{}, false, fmt.Errorf("invalid USD cost")
+		}
+
+		return model.ResourceGroupCost{
+			SubscriptionId:   subscriptionId,
+			SubscriptionName: value("subscriptionName"),
+			Name:             value("resourceGroup"),
+			BillingPeriod:    billingPeriod,
+			Cost:             cost,
+			CostUSD:          costUSD,
+			Currency:         currency,
+		}, true, nil
+	}, nil
+}
+
+// mapColumns returns the index of each of the named columns found in the header.
+func mapColumns(header []string, names map[string][]string) map[string]int {
+	normalized := make(map[string]int)
+	for i, name := range header {
+		name = normalizeColumnName(strings.TrimPrefix(name, "\ufeff"))
+		if _, ok := normalized[name]; !ok {
+			normalized[name] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	for column, alternatives := range names {
+		for _, name := range alternatives {
+			if i, ok := normalized[name]; ok {
+				columns[column] = i
+				break
+			}
+		}
+	}
+
+	return columns
+}
+
+func normalizeColumnName(name string) string {
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// parseBillingPeriod returns the first day of the billing period, preferring the billing period start date where the
+// export includes one and otherwise using the month of the usage date.
+func parseBillingPeriod(billingPeriod string, date string) (time.Time, error) {
+	value := billingPeriod
+	if len(value) == 0 {
+		value = date
+	}
+
+	for _, layout := range dateLayouts {
+		if d, err := time.Parse(layout, value); err == nil {
+			return time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognised date '%s'", value)
+}
+
+func parseAmount(value string) (float64, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// usdAmount returns the cost in USD, using the cost itself where no USD value is available and the currency is USD.
+func usdAmount(value string, cost float64, currency string) (float64, error) {
+	if len(value) > 0 {
+		return parseAmount(value)
+	} else if strings.EqualFold(currency, "USD") {
+		return cost, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,218 @@
+package exports
+
+import (
+	"compress/gzip"
+	"github.com/dazfuller/azcosts/internal/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeExportFile writes the content to a file in the directory, compressing it when the name ends with .gz.
+func writeExportFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+
+	exportPath := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(exportPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Create(exportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(name, ".gz") {
+		gz := gzip.NewWriter(file)
+		if _, err := gz.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	} else if _, err := file.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	return exportPath
+}
+
+func TestLoad(t *testing.T) {
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		options Options
+		want    []model.ResourceGroupCost
+	}{
+		{
+			name: "enterprise agreement actual costs",
+			files: map[string]string{
+				"ea.csv": "\ufeffSubscriptionId,SubscriptionName,ResourceGroup,CostInBillingCurrency,BillingCurrencyCode,BillingPeriodStartDate,Date\n" +
+					"AAAA,Production,rg-web,10.5,GBP,03/01/2024,03/15/2024\n" +
+					"aaaa,Production,RG-WEB,4.5,GBP,03/01/2024,03/16/2024\n" +
+					"aaaa,Production,rg-data,1,GBP,03/01/2024,03/16/2024\n",
+			},
+			want: []model.ResourceGroupCost{
+				{SubscriptionId: "aaaa", SubscriptionName: "Production", Name: "rg-data", BillingPeriod: march, Cost: 1, Currency: "GBP"},
+				{SubscriptionId: "aaaa", SubscriptionName: "Production", Name: "rg-web", BillingPeriod: march, Cost: 15, Currency: "GBP"},
+			},
+		},
+		{
+			name: "pay-as-you-go without billing period",
+			files: map[string]string{
+				"payg.csv": "SubscriptionGuid,ResourceGroupName,PreTaxCost,Currency,UsageDateTime\n" +
+					"bbbb,rg-app,2.25,USD,2024-04-02T00:00:00Z\n" +
+					"bbbb,rg-app,0.75,USD,2024-04-03\n" +
+					",rg-unassigned,100,USD,2024-04-03\n",
+			},
+			want: []model.ResourceGroupCost{
+				{SubscriptionId: "bbbb", Name: "rg-app", BillingPeriod: april, Cost: 3, CostUSD: 3, Currency: "USD"},
+			},
+		},
+		{
+			name: "microsoft customer agreement with usd costs",
+			files: map[string]string{
+				"mca.csv": "subscriptionId,subscriptionName,resourceGroupName,costInBillingCurrency,costInUsd,billingCurrency,billingPeriodStartDate\n" +
+					"cccc,Development,rg-test,8,10,EUR,2024-03-01 00:00:00\n",
+			},
+			want: []model.ResourceGroupCost{
+				{SubscriptionId: "cccc", SubscriptionName: "Development", Name: "rg-test", BillingPeriod: march, Cost: 8, CostUSD: 10, Currency: "EUR"},
+			},
+		},
+		{
+			name: "compressed files in export directories",
+			files: map[string]string{
+				"daily/20240301-20240331/part_0.csv.gz": "SubscriptionId,ResourceGroup,Cost,Date\n" +
+					"dddd,rg-one,1,2024-03-05\n",
+				"daily/20240301-20240331/part_1.csv": "SubscriptionId,ResourceGroup,Cost,Date\n" +
+					"dddd,rg-one,2,2024-03-06T10:00:00\n",
+				"daily/manifest.json": "{}",
+			},
+			want: []model.ResourceGroupCost{
+				{SubscriptionId: "dddd", Name: "rg-one", BillingPeriod: march, Cost: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeExportFile(t, dir, name, content)
+			}
+
+			costs, err := Load([]string{dir}, tt.options)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			if len(costs) != len(tt.want) {
+				t.Fatalf("Load() = %+v, want %+v", costs, tt.want)
+			}
+			for i := range tt.want {
+				if costs[i] != tt.want[i] {
+					t.Errorf("Load() cost %d = %+v, want %+v", i, costs[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		options Options
+		wantErr string
+	}{
+		{
+			name:    "empty file",
+			content: "",
+			wantErr: "does not contain a header row",
+		},
+		{
+			name:    "missing cost column",
+			content: "SubscriptionId,ResourceGroup,Date\naaaa,rg,2024-03-01\n",
+			wantErr: "does not contain a cost column",
+		},
+		{
+			name:    "missing date columns",
+			content: "SubscriptionId,ResourceGroup,Cost\naaaa,rg,1\n",
+			wantErr: "does not contain a billing period or date column",
+		},
+		{
+			name:    "invalid cost",
+			content: "SubscriptionId,ResourceGroup,Cost,Date\naaaa,rg,1,2024-03-01\naaaa,rg,lots,2024-03-01\n",
+			wantErr: "invalid cost on line 3",
+		},
+		{
+			name:    "invalid date",
+			content: "SubscriptionId,ResourceGroup,Cost,Date\naaaa,rg,1,1st March\n",
+			wantErr: "invalid date on line 2",
+		},
+		{
+			name:    "unsupported cost type",
+			content: "SubscriptionId,ResourceGroup,Cost,Date\n",
+			options: Options{CostType: "amortized"},
+			wantErr: "unsupported cost type 'amortized'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exportPath := writeExportFile(t, t.TempDir(), "export.csv", tt.content)
+
+			_, err := Load([]string{exportPath}, tt.options)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadWithoutFiles(t *testing.T) {
+	_, err := Load([]string{t.TempDir()}, Options{})
+	if err == nil || !strings.Contains(err.Error(), "no export files found") {
+		t.Errorf("Load() error = %v, want no export files found", err)
+	}
+}
+
+func TestParseBillingPeriod(t *testing.T) {
+	tests := []struct {
+		billingPeriod string
+		date          string
+		want          string
+	}{
+		{billingPeriod: "03/01/2024", date: "04/15/2024", want: "2024-03"},
+		{date: "12/31/2023", want: "2023-12"},
+		{date: "2024-02-29", want: "2024-02"},
+		{date: "2024-05-31T23:59:59Z", want: "2024-05"},
+		{date: "2024-05-31T23:59:59+02:00", want: "2024-05"},
+		{date: "2024-06-01T00:00:00", want: "2024-06"},
+		{date: "2024-07-01 12:30:00", want: "2024-07"},
+		{date: "08/20/2024 12:30:00", want: "2024-08"},
+		{date: "20/08/2024", want: ""},
+		{date: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.billingPeriod+tt.date, func(t *testing.T) {
+			got, err := parseBillingPeriod(tt.billingPeriod, tt.date)
+			if len(tt.want) == 0 {
+				if err == nil {
+					t.Errorf("parseBillingPeriod() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil || got.Format("2006-01") != tt.want || got.Day() != 1 {
+				t.Errorf("parseBillingPeriod() = %v, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package exports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// focusColumnNames lists the FOCUS columns used, including the Azure specific "x_" columns where Azure provides values
+// which are not part of the specification. Names are normalized before being compared.
+var focusColumnNames = map[string][]string{
+	"billedCost":         {"billedcost"},
+	"effectiveCost":      {"effectivecost"},
+	"billedCostUSD":      {"xbilledcostinusd"},
+	"effectiveCostUSD":   {"xeffectivecostinusd"},
+	"currency":           {"billingcurrency"},
+	"chargePeriodStart":  {"chargeperiodstart"},
+	"billingPeriodStart": {"billingperiodstart"},
+	"subAccountId":       {"subaccountid"},
+	"subAccountName":     {"subaccountname"},
+	"resourceGroup":      {"xresourcegroupname"},
+	"resourceId":         {"resourceid"},
+	"tags":               {"tags"},
+}
+
+// isFocusHeader returns true if the columns are those of a FOCUS file rather than a Cost Management export.
+func isFocusHeader(header []string) bool {
+	columns := mapColumns(header, focusColumnNames)
+	_, hasBilledCost := columns["billedCost"]
+	_, hasChargePeriod := columns["chargePeriodStart"]
+	return hasBilledCost && hasChargePeriod
+}
+
+// focusRowMapper returns the mapper for the rows of a FOCUS file. The sub account is used as the subscription, and
+// the resource group is taken from the resource group tag if one is selected, then the Azure resource group column,
+// and finally from the resource id.
+func focusRowMapper(columns map[string]int, options Options) (rowMapper, error) {
+	costColumn, usdColumn := "billedCost", "billedCostUSD"
+	if options.CostType == EffectiveCost {
+		costColumn, usdColumn = "effectiveCost", "effectiveCostUSD"
+	}
+
+	for _, required := range []string{"subAccountId", costColumn} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("file does not contain a %s column", required)
+		}
+	}
+
+	return func(value func(string) string) (model.ResourceGroupCost, bool, error) {
+		subscriptionId := subscriptionFromSubAccount(value("subAccountId"))
+		if len(subscriptionId) == 0 {
+			return model.ResourceGroupCost{}, false, nil
+		}
+
+		billingPeriod, err := parseBillingPeriod(value("chargePeriodStart"), value("billingPeriodStart"))
+		if err != nil {
+			return model.ResourceGroupCost{}, false, fmt.Errorf("invalid charge period")
+		}
+
+		cost, err := parseAmount(value(costColumn))
+		if err != nil {
+			return model.ResourceGroupCost{}, false, fmt.Errorf("invalid cost")
+		}
+
+		currency := value("currency")
+
+		costUSD, err := usdAmount(value(usdColumn), cost, currency)
+		if err != nil {
+			return model.ResourceGroupCost{}, false, fmt.Errorf("invalid USD cost")
+		}
+
+		resourceGroup := ""
+		if len(options.ResourceGroupTag) > 0 {
+			resourceGroup = tagValue(value("tags"), options.ResourceGroupTag)
+		}
+		if len(resourceGroup) == 0 {
+			resourceGroup = value("resourceGroup")
+		}
+		if len(resourceGroup) == 0 {
+			resourceGroup = resourceGroupFromId(value("resourceId"))
+		}
+
+		return model.ResourceGroupCost{
+			SubscriptionId:   subscriptionId,
+			SubscriptionName: value("subAccountName"),
+			Name:             resourceGroup,
+			BillingPeriod:    billingPeriod,
+			Cost:             cost,
+			CostUSD:          costUSD,
+			Currency:         currency,
+		}, true, nil
+	}, nil
+}
+
+// subscriptionFromSubAccount returns the subscription id from an Azure sub account id, which is the resource id of the
+// subscription. Sub accounts from other providers are returned unchanged.
+func subscriptionFromSubAccount(subAccountId string) string {
+	subAccountId = strings.ToLower(subAccountId)
+	if _, id, ok := strings.Cut(subAccountId, "/subscriptions/"); ok {
+		id, _, _ = strings.Cut(id, "/")
+		return id
+	}
+	return subAccountId
+}
+
+func resourceGroupFromId(resourceId string) string {
+	parts := strings.Split(resourceId, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if strings.EqualFold(parts[i], "resourceGroups") {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// tagValue returns the value of the named tag, ignoring case, from tags stored as a JSON object. Older exports omit the
+// surrounding braces, and so these are added where missing.
+func tagValue(tags string, name string) string {
+	tags = strings.TrimSpace(tags)
+	if len(tags) == 0 {
+		return ""
+	}
+	if !strings.HasPrefix(tags, "{") {
+		tags = "{" + tags + "}"
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal([]byte(tags), &values); err != nil {
+		return ""
+	}
+
+	for key, value := range values {
+		if strings.EqualFold(key, name) {
+			if s, ok := value.(string); ok {
+				return s
+			}
+			return fmt.Sprint(value)
+		}
+	}
+
+	return ""
+}
+
+func readParquetFile(path string, options Options, add func(model.ResourceGroupCost)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	pf, err := parquet.OpenFile(file, info.Size())
+	if err != nil {
+		return err
+	}
+
+	schema := pf.Schema()
+	fields := schema.Fields()
+	header := make([]string, len(fields))
+	for i, field := range fields {
+		header[i] = field.Name()
+	}
+
+	if !isFocusHeader(header) {
+		return fmt.Errorf("parquet files must use the FOCUS format")
+	}
+
+	columns := mapColumns(header, focusColumnNames)
+	mapRow, err := focusRowMapper(columns, options)
+	if err != nil {
+		return err
+	}
+
+	// Values are read from the leaf columns of the top level fields, with the exception of tags which can be stored
+	// as a map of key and value leaf columns rather than a JSON string.
+	leaves := make(map[string]parquet.LeafColumn)
+	tagKeyColumn, tagValueColumn := -1, -1
+	for _, columnPath := range schema.Columns() {
+		leaf, ok := schema.Lookup(columnPath...)
+		if !ok {
+			continue
+		}
+
+		if len(columnPath) == 1 {
+			leaves[columnPath[0]] = leaf
+		} else if i, ok := columns["tags"]; ok && columnPath[0] == header[i] {
+			switch columnPath[len(columnPath)-1] {
+			case "key":
+				tagKeyColumn = leaf.ColumnIndex
+			case "value":
+				tagValueColumn = leaf.ColumnIndex
+			}
+		}
+	}
+
+	reader := parquet.NewReader(pf)
+	defer reader.Close()
+
+	rows := make([]parquet.Row, 100)
+	line := 0
+
+	for {
+		n, err := reader.ReadRows(rows)
+
+		for _, row := range rows[:n] {
+			line++
+
+			values := make(map[int][]parquet.Value)
+			for _, v := range row {
+				values[v.Column()] = append(values[v.Column()], v)
+			}
+
+			cost, ok, mapErr := mapRow(func(column string) string {
+				i, ok := columns[column]
+				if !ok {
+					return ""
+				}
+
+				if leaf, ok := leaves[header[i]]; ok {
+					for _, v := range values[leaf.ColumnIndex] {
+						if !v.IsNull() {
+							return strings.TrimSpace(parquetString(parquetValue(v), leaf.Node))
+						}
+					}
+				} else if column == "tags" && tagKeyColumn >= 0 && tagValueColumn >= 0 {
+					return parquetTags(values[tagKeyColumn], values[tagValueColumn])
+				}
+
+				return ""
+			})
+			if mapErr != nil {
+				return fmt.Errorf("%s on row %d", mapErr.Error(), line)
+			} else if ok {
+				add(cost)
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parquetValue(v parquet.Value) any {
+	switch v.Kind() {
+	case parquet.Boolean:
+		return v.Boolean()
+	case parquet.Int32:
+		return v.Int32()
+	case parquet.Int64:
+		return v.Int64()
+	case parquet.Float:
+		return v.Float()
+	case parquet.Double:
+		return v.Double()
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		return v.ByteArray()
+	default:
+		return v.String()
+	}
+}
+
+// parquetTags returns the tags stored as a map of key and value columns as a JSON object.
+func parquetTags(keys []parquet.Value, values []parquet.Value) string {
+	tags := make(map[string]string)
+	for i := 0; i < len(keys) && i < len(values); i++ {
+		if !keys[i].IsNull() {
+			tags[string(keys[i].ByteArray())] = string(values[i].ByteArray())
+		}
+	}
+
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// parquetString converts a parquet value to the same string representation it would have in a CSV file, using the
+// logical type of the column to convert dates, timestamps, and decimals.
+func parquetString(value any, node parquet.Node) string {
+	logicalType := node.Type().LogicalType()
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		if logicalType != nil && logicalType.Decimal != nil {
+			unscaled := new(big.Int).SetBytes(v)
+			if len(v) > 0 && v[0]&0x80 != 0 {
+				unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(len(v)*8)))
+			}
+			f, _ := new(big.Float).SetInt(unscaled).Float64()
+			return strconv.FormatFloat(f/math.Pow10(int(logicalType.Decimal.Scale)), 'f', -1, 64)
+		}
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case int32:
+		return parquetInteger(int64(v), logicalType)
+	case int64:
+		return parquetInteger(v, logicalType)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+		return fmt.Sprint(v)
+	}
+}
+
+func parquetInteger(value int64, logicalType *format.LogicalType) string {
+	if logicalType != nil {
+		switch {
+		case logicalType.Date != nil:
+			return time.Unix(value*24*60*60, 0).UTC().Format(time.RFC3339)
+		case logicalType.Timestamp != nil && logicalType.Timestamp.Unit.Millis != nil:
+			return time.UnixMilli(value).UTC().Format(time.RFC3339)
+		case logicalType.Timestamp != nil && logicalType.Timestamp.Unit.Micros != nil:
+			return time.UnixMicro(value).UTC().Format(time.RFC3339)
+		case logicalType.Timestamp != nil:
+			return time.Unix(0, value).UTC().Format(time.RFC3339)
+		case logicalType.Decimal != nil:
+			return strconv.FormatFloat(float64(value)/math.Pow10(int(logicalType.Decimal.Scale)), 'f', -1, 64)
+		}
+	}
+	return strconv.FormatInt(value, 10)
+}
@@ -0,0 +1,206 @@
+package exports
+
+import (
+	"github.com/dazfuller/azcosts/internal/model"
+	"github.com/parquet-go/parquet-go"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const focusHeader = "BilledCost,EffectiveCost,x_BilledCostInUsd,BillingCurrency,ChargePeriodStart,BillingPeriodStart,SubAccountId,SubAccountName,x_ResourceGroupName,ResourceId,Tags\n"
+
+func TestLoadFocusCsv(t *testing.T) {
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	rows := `10,8,12.5,GBP,2024-03-02T00:00:00Z,2024-03-01T00:00:00Z,/subscriptions/AAAA,Production,rg-web,,"{""team"":""platform""}"
+5,4,6,GBP,2024-03-03T00:00:00Z,2024-03-01T00:00:00Z,/subscriptions/aaaa,Production,,/subscriptions/aaaa/resourceGroups/rg-data/providers/x/y,"""Team"":""data"""
+3,3,,USD,2024-03-03T00:00:00Z,2024-03-01T00:00:00Z,123456789012,Other cloud,,,
+1,1,1,USD,2024-03-03T00:00:00Z,2024-03-01T00:00:00Z,,Unassigned,,,
+`
+
+	tests := []struct {
+		name    string
+		options Options
+		want    []model.ResourceGroupCost
+	}{
+		{
+			name: "billed cost",
+			want: []model.ResourceGroupCost{
+				{SubscriptionId: "123456789012", SubscriptionName: "Other cloud", BillingPeriod: march, Cost: 3, CostUSD: 3, Currency: "USD"},
+				{SubscriptionId: "aaaa", SubscriptionName: "Production", Name: "rg-data", BillingPeriod: march, Cost: 5, CostUSD: 6, Currency: "GBP"},
+				{SubscriptionId: "aaaa", SubscriptionName: "Production", Name: "rg-web", BillingPeriod: march, Cost: 10, CostUSD: 12.5, Currency: "GBP"},
+			},
+		},
+		{
+			name:    "effective cost",
+			options: Options{CostType: EffectiveCost},
+			want: []model.ResourceGroupCost{
+				{SubscriptionId: "123456789012", SubscriptionName: "Other cloud", BillingPeriod: march, Cost: 3, CostUSD: 3, Currency: "USD"},
+				{SubscriptionId: "aaaa", SubscriptionName: "Production", Name: "rg-data", BillingPeriod: march, Cost: 4, Currency: "GBP"},
+				{SubscriptionId: "aaaa", SubscriptionName: "Production", Name: "rg-web", BillingPeriod: march, Cost: 8, Currency: "GBP"},
+			},
+		},
+		{
+			name:    "resource group tag",
+			options: Options{ResourceGroupTag: "TEAM"},
+			want: []model.ResourceGroupCost{
+				{SubscriptionId: "123456789012", SubscriptionName: "Other cloud", BillingPeriod: march, Cost: 3, CostUSD: 3, Currency: "USD"},
+				{SubscriptionId: "aaaa", SubscriptionName: "Production", Name: "data", BillingPeriod: march, Cost: 5, CostUSD: 6, Currency: "GBP"},
+				{SubscriptionId: "aaaa", SubscriptionName: "Production", Name: "platform", BillingPeriod: march, Cost: 10, CostUSD: 12.5, Currency: "GBP"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exportPath := writeExportFile(t, t.TempDir(), "focus.csv", focusHeader+rows)
+
+			costs, err := Load([]string{exportPath}, tt.options)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			if len(costs) != len(tt.want) {
+				t.Fatalf("Load() = %+v, want %+v", costs, tt.want)
+			}
+			for i := range tt.want {
+				if costs[i] != tt.want[i] {
+					t.Errorf("Load() cost %d = %+v, want %+v", i, costs[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+type focusParquetRow struct {
+	BilledCost        int64             `parquet:"BilledCost,decimal(2:18)"`
+	EffectiveCost     float64           `parquet:"EffectiveCost"`
+	BillingCurrency   string            `parquet:"BillingCurrency"`
+	ChargePeriodStart time.Time         `parquet:"ChargePeriodStart,timestamp(millisecond)"`
+	SubAccountId      string            `parquet:"SubAccountId"`
+	SubAccountName    string            `parquet:"SubAccountName,optional"`
+	ResourceId        string            `parquet:"ResourceId"`
+	Tags              map[string]string `parquet:"Tags"`
+}
+
+func TestReadParquetFile(t *testing.T) {
+	parquetPath := filepath.Join(t.TempDir(), "focus.parquet")
+	err := parquet.WriteFile(parquetPath, []focusParquetRow{
+		{
+			BilledCost:        -1250,
+			EffectiveCost:     -12,
+			BillingCurrency:   "EUR",
+			ChargePeriodStart: time.Date(2024, 4, 30, 23, 0, 0, 0, time.UTC),
+			SubAccountId:      "/subscriptions/BBBB",
+			ResourceId:        "/subscriptions/bbbb/resourceGroups/rg-credit/providers/x/y",
+			Tags:              map[string]string{"Team": "finance"},
+		},
+		{
+			BilledCost:        10050,
+			EffectiveCost:     99.5,
+			BillingCurrency:   "EUR",
+			ChargePeriodStart: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			SubAccountId:      "/subscriptions/bbbb",
+			SubAccountName:    "Finance",
+			ResourceId:        "/subscriptions/bbbb/resourceGroups/rg-app/providers/x/y",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		options Options
+		want    []model.ResourceGroupCost
+	}{
+		{
+			name:    "billed decimal cost",
+			options: Options{CostType: BilledCost},
+			want: []model.ResourceGroupCost{
+				{SubscriptionId: "bbbb", Name: "rg-credit", BillingPeriod: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), Cost: -12.5, Currency: "EUR"},
+				{SubscriptionId: "bbbb", SubscriptionName: "Finance", Name: "rg-app", BillingPeriod: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Cost: 100.5, Currency: "EUR"},
+			},
+		},
+		{
+			name:    "effective cost and map tags",
+			options: Options{CostType: EffectiveCost, ResourceGroupTag: "team"},
+			want: []model.ResourceGroupCost{
+				{SubscriptionId: "bbbb", Name: "finance", BillingPeriod: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), Cost: -12, Currency: "EUR"},
+				{SubscriptionId: "bbbb", SubscriptionName: "Finance", Name: "rg-app", BillingPeriod: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Cost: 99.5, Currency: "EUR"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var costs []model.ResourceGroupCost
+			err := readParquetFile(parquetPath, tt.options, func(cost model.ResourceGroupCost) {
+				costs = append(costs, cost)
+			})
+			if err != nil {
+				t.Fatalf("readParquetFile() error = %v", err)
+			}
+
+			if len(costs) != len(tt.want) {
+				t.Fatalf("readParquetFile() = %+v, want %+v", costs, tt.want)
+			}
+			for i := range tt.want {
+				if costs[i] != tt.want[i] {
+					t.Errorf("readParquetFile() cost %d = %+v, want %+v", i, costs[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadParquetFileRequiresFocus(t *testing.T) {
+	type exportRow struct {
+		SubscriptionId string  `parquet:"SubscriptionId"`
+		Cost           float64 `parquet:"Cost"`
+	}
+
+	parquetPath := filepath.Join(t.TempDir(), "export.parquet")
+	if err := parquet.WriteFile(parquetPath, []exportRow{{SubscriptionId: "aaaa", Cost: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := readParquetFile(parquetPath, Options{}, func(model.ResourceGroupCost) {})
+	if err == nil || !strings.Contains(err.Error(), "must use the FOCUS format") {
+		t.Errorf("readParquetFile() error = %v, want must use the FOCUS format", err)
+	}
+}
+
+func TestParquetString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		node  parquet.Node
+		want  string
+	}{
+		{name: "null", value: nil, node: parquet.String(), want: ""},
+		{name: "string", value: []byte("GBP"), node: parquet.String(), want: "GBP"},
+		{name: "double", value: 12.25, node: parquet.Leaf(parquet.DoubleType), want: "12.25"},
+		{name: "float", value: float32(0.5), node: parquet.Leaf(parquet.FloatType), want: "0.5"},
+		{name: "integer", value: int64(42), node: parquet.Leaf(parquet.Int64Type), want: "42"},
+		{name: "int32 decimal", value: int32(-1234), node: parquet.Decimal(2, 9, parquet.Int32Type), want: "-12.34"},
+		{name: "int64 decimal", value: int64(123456), node: parquet.Decimal(4, 18, parquet.Int64Type), want: "12.3456"},
+		{name: "positive byte decimal", value: []byte{0x00, 0x30, 0x39}, node: parquet.Decimal(3, 20, parquet.FixedLenByteArrayType(3)), want: "12.345"},
+		{name: "negative byte decimal", value: []byte{0xff, 0xcf, 0xc7}, node: parquet.Decimal(3, 20, parquet.FixedLenByteArrayType(3)), want: "-12.345"},
+		{name: "minus one byte decimal", value: []byte{0xff}, node: parquet.Decimal(0, 2, parquet.FixedLenByteArrayType(1)), want: "-1"},
+		{name: "date", value: int32(19783), node: parquet.Date(), want: "2024-03-01T00:00:00Z"},
+		{name: "millisecond timestamp", value: int64(1709251200000), node: parquet.Timestamp(parquet.Millisecond), want: "2024-03-01T00:00:00Z"},
+		{name: "microsecond timestamp", value: int64(1709251200000000), node: parquet.Timestamp(parquet.Microsecond), want: "2024-03-01T00:00:00Z"},
+		{name: "nanosecond timestamp", value: int64(1709251200000000000), node: parquet.Timestamp(parquet.Nanosecond), want: "2024-03-01T00:00:00Z"},
+		{name: "time", value: time.Date(2024, 3, 1, 1, 0, 0, 0, time.FixedZone("CET", 3600)), node: parquet.String(), want: "2024-03-01T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parquetString(tt.value, tt.node); got != tt.want {
+				t.Errorf("parquetString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
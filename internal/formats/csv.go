@@ -4,40 +4,25 @@ import (
 	"encoding/csv"
 	"fmt"
 	"github.com/dazfuller/azcosts/internal/model"
-	"os"
+	"io"
 	"strconv"
 )
 
 type CsvFormatter struct {
-	useStdOut  bool
-	outputPath string
+	grouping Grouping
 }
 
-func MakeCsvFormatter(useStdOut bool, outputPath string) (CsvFormatter, error) {
-	if err := validateOptions(useStdOut, outputPath); err != nil {
-		return CsvFormatter{}, err
-	}
-
-	return CsvFormatter{useStdOut: useStdOut, outputPath: outputPath}, nil
+func MakeCsvFormatter(grouping Grouping) CsvFormatter {
+	return CsvFormatter{grouping: grouping}
 }
 
-func (cf CsvFormatter) Generate(costs []model.ResourceGroupSummary) error {
-	var writer *csv.Writer
-
-	if cf.useStdOut {
-		writer = csv.NewWriter(os.Stdout)
-	} else {
-		file, err := os.Create(cf.outputPath)
-		if err != nil {
-			return err
-		}
-		writer = csv.NewWriter(file)
-	}
+func (cf CsvFormatter) Generate(w io.Writer, costs []model.ResourceGroupSummary) error {
+	writer := csv.NewWriter(w)
 
 	// Write header
-	header := []string{"Name", "Subscription Name", "Active"}
+	header := []string{"Name", cf.grouping.Parent + " Name", "Active"}
 	for _, cost := range costs[0].Costs {
-		header = append(header, cost.Period)
+		header = append(header, PeriodLabel(cost))
 	}
 	header = append(header, "Total Costs")
 	err := writer.Write(header)
@@ -59,5 +44,38 @@ func (cf CsvFormatter) Generate(costs []model.ResourceGroupSummary) error {
 	}
 
 	writer.Flush()
-	return nil
+	return writer.Error()
+}
+
+func (cf CsvFormatter) GenerateBudgets(w io.Writer, utilisation []model.BudgetUtilisation) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"Name", "Subscription", "Resource Group", "Period", "Budget", "Actual", "Utilisation", "Threshold", "Overrun", "Breached"}
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, bu := range utilisation {
+		record := []string{
+			bu.Name,
+			bu.Subscription,
+			bu.ResourceGroup,
+			bu.Period,
+			fmt.Sprintf("%.2f", bu.Amount),
+			fmt.Sprintf("%.2f", bu.Actual),
+			fmt.Sprintf("%.2f", bu.Utilisation),
+			fmt.Sprintf("%.2f", bu.Threshold),
+			fmt.Sprintf("%.2f", bu.Overrun),
+			strconv.FormatBool(bu.Breached),
+		}
+
+		err := writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
 }
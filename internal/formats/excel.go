@@ -4,24 +4,31 @@ import (
 	"fmt"
 	"github.com/dazfuller/azcosts/internal/model"
 	"github.com/xuri/excelize/v2"
+	"io"
 	"log"
+	"slices"
+	"strconv"
+	"strings"
 )
 
-const firstCol = "A"
+const (
+	firstCol       = "A"
+	dataSheetName  = "Data"
+	pivotSheetName = "Pivot"
+)
 
 type ExcelFormatter struct {
-	outputPath string
+	grouping Grouping
+	data     []model.CostRecord
 }
 
-func MakeExcelFormatter(outputPath string) (ExcelFormatter, error) {
-	if err := validateOptions(false, outputPath); err != nil {
-		return ExcelFormatter{}, err
-	}
-
-	return ExcelFormatter{outputPath: outputPath}, nil
+// MakeExcelFormatter returns a formatter which writes the costs to a workbook. When data is provided the workbook also
+// includes a sheet of every cost record, and a pivot table over it, so that the costs can be pivoted in other ways.
+func MakeExcelFormatter(grouping Grouping, data []model.CostRecord) ExcelFormatter {
+	return ExcelFormatter{grouping: grouping, data: data}
 }
 
-func (ef ExcelFormatter) Generate(costs []model.ResourceGroupSummary) error {
+func (ef ExcelFormatter) Generate(w io.Writer, costs []model.ResourceGroupSummary) error {
 	f := excelize.NewFile()
 	defer func(f *excelize.File) {
 		err := f.Close()
@@ -30,79 +37,165 @@ func (ef ExcelFormatter) Generate(costs []model.ResourceGroupSummary) error {
 		}
 	}(f)
 
-	_, _ = f.NewSheet("Sheet2")
-
 	subscriptionSummary := generateSubscriptionSummary(costs)
 	err := ef.createSubscriptionSummarySheet(subscriptionSummary, f)
 	if err != nil {
 		return err
 	}
 
-	err = ef.createCostsSheet(costs, f)
+	err = ef.createCostsSheet(f, "Costs", "CostSummary", costs)
 	if err != nil {
 		return err
 	}
 
-	if err = f.SaveAs(ef.outputPath); err != nil {
+	// Add a sheet of the costs of each subscription, in the same order as the summary
+	sheetNames := []string{ef.grouping.Parent + "s", "Costs", pivotSheetName, dataSheetName}
+	tablePrefix := strings.ReplaceAll(ef.grouping.Parent, " ", "")
+	for i, sub := range subscriptionSummary {
+		var subscriptionCosts []model.ResourceGroupSummary
+		for _, cost := range costs {
+			if cost.SubscriptionName == sub.Name {
+				subscriptionCosts = append(subscriptionCosts, cost)
+			}
+		}
+
+		sheetName := uniqueSheetName(sub.Name, sheetNames)
+		sheetNames = append(sheetNames, sheetName)
+
+		err = ef.createCostsSheet(f, sheetName, fmt.Sprintf("%sCosts%d", tablePrefix, i+1), subscriptionCosts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(ef.data) > 0 {
+		err = ef.createDataSheet(f)
+		if err != nil {
+			return err
+		}
+
+		err = ef.createPivotSheet(f)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = f.Write(w); err != nil {
 		return fmt.Errorf("an error occured saving the workbook: %s", err.Error())
 	}
 
 	return nil
 }
 
-func (ef ExcelFormatter) createSubscriptionSummarySheet(subscriptions []model.SubscriptionSummary, f *excelize.File) error {
-	sheetName := "Subscriptions"
+func (ef ExcelFormatter) GenerateBudgets(w io.Writer, utilisation []model.BudgetUtilisation) error {
+	f := excelize.NewFile()
+	defer func(f *excelize.File) {
+		err := f.Close()
+		if err != nil {
+			log.Printf("Unable to close Excel workbook")
+		}
+	}(f)
+
+	sheetName := "Budgets"
 
 	err := f.SetSheetName("Sheet1", sheetName)
 	if err != nil {
 		return err
 	}
 
-	headers := []string{
-		"Subscription",
+	headers := []string{"Name", "Subscription", "Resource Group", "Period", "Budget", "Actual", "Utilisation", "Threshold", "Overrun", "Breached"}
+	err = f.SetSheetRow(sheetName, "A1", &headers)
+	if err != nil {
+		return fmt.Errorf("unable to set header row in budgets sheet: %v", err)
 	}
 
-	err = ef.addHeaders(f, sheetName, subscriptions[0].Costs, headers, true)
-	if err != nil {
-		return err
+	for i, bu := range utilisation {
+		rowStart, _ := excelize.JoinCellName("A", i+2)
+		row := []interface{}{
+			bu.Name,
+			bu.Subscription,
+			bu.ResourceGroup,
+			bu.Period,
+			bu.Amount,
+			bu.Actual,
+			bu.Utilisation / 100,
+			bu.Threshold / 100,
+			bu.Overrun,
+			bu.Breached,
+		}
+
+		err := f.SetSheetRow(sheetName, rowStart, &row)
+		if err != nil {
+			return fmt.Errorf("unable to add data row to budgets worksheet: %v", err)
+		}
 	}
 
-	err = ef.addSubscriptionCostDataToSheet(f, sheetName, subscriptions)
+	customNumFmt := "#,##0.00;(#,##0.00);-"
+	percentNumFmt := "0.0%"
+	costStyle, _ := f.NewStyle(&excelize.Style{CustomNumFmt: &customNumFmt})
+	percentStyle, _ := f.NewStyle(&excelize.Style{CustomNumFmt: &percentNumFmt})
+
+	_ = f.SetColWidth(sheetName, "A", "C", 40)
+	_ = f.SetColWidth(sheetName, "D", "J", 15)
+	_ = f.SetColStyle(sheetName, "E:F", costStyle)
+	_ = f.SetColStyle(sheetName, "G:H", percentStyle)
+	_ = f.SetColStyle(sheetName, "I", costStyle)
+
+	if len(utilisation) > 0 {
+		err = ef.addTable(f, sheetName, "BudgetUtilisation", len(utilisation), "J")
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = f.Write(w); err != nil {
+		return fmt.Errorf("an error occured saving the workbook: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (ef ExcelFormatter) createSubscriptionSummarySheet(subscriptions []model.SubscriptionSummary, f *excelize.File) error {
+	sheetName := ef.grouping.Parent + "s"
+
+	err := f.SetSheetName("Sheet1", sheetName)
 	if err != nil {
 		return err
 	}
 
-	cols, _ := f.GetCols(sheetName)
-	lastCol, _ := excelize.ColumnNumberToName(len(cols))
+	headers := []string{
+		ef.grouping.Parent,
+	}
 
-	err = ef.setSheetFormats(f, sheetName, cols, 1)
+	err = ef.addHeaders(f, sheetName, subscriptions[0].Costs, headers, true)
 	if err != nil {
 		return err
 	}
 
-	err = ef.addSparkLines(f, sheetName, 1)
+	err = ef.addSubscriptionCostDataToSheet(f, sheetName, subscriptions)
 	if err != nil {
 		return err
 	}
 
-	err2 := ef.addTable(f, sheetName, "SubscriptionSummary", len(subscriptions), lastCol)
-	if err2 != nil {
-		return err2
+	err = ef.formatSheet(f, sheetName, "SubscriptionSummary", len(subscriptions), 1)
+	if err != nil {
+		return err
 	}
-	return nil
-}
 
-func (ef ExcelFormatter) createCostsSheet(costs []model.ResourceGroupSummary, f *excelize.File) error {
-	sheetName := "Costs"
+	return ef.addSubscriptionChart(f, sheetName, subscriptions)
+}
 
-	err := f.SetSheetName("Sheet2", sheetName)
+// createCostsSheet adds a sheet listing the costs, used for both the sheet of all costs and the sheet of each
+// subscription.
+func (ef ExcelFormatter) createCostsSheet(f *excelize.File, sheetName string, tableName string, costs []model.ResourceGroupSummary) error {
+	_, err := f.NewSheet(sheetName)
 	if err != nil {
 		return err
 	}
 
 	headers := []string{
-		"Resource Group",
-		"Subscription",
+		ef.grouping.Name,
+		ef.grouping.Parent,
 		"Active",
 	}
 
@@ -116,23 +209,124 @@ func (ef ExcelFormatter) createCostsSheet(costs []model.ResourceGroupSummary, f
 		return err
 	}
 
+	return ef.formatSheet(f, sheetName, tableName, len(costs), 3)
+}
+
+// formatSheet adds a totals row below the data, formats the columns, and adds the sparklines, table, and frozen
+// header panes to the sheet.
+func (ef ExcelFormatter) formatSheet(f *excelize.File, sheetName string, tableName string, rowCount int, fixedCellCount int) error {
 	cols, _ := f.GetCols(sheetName)
 	lastCol, _ := excelize.ColumnNumberToName(len(cols))
 
-	err = ef.setSheetFormats(f, sheetName, cols, 3)
+	err := ef.addTotalsRow(f, sheetName, rowCount, fixedCellCount, len(cols)-1)
+	if err != nil {
+		return err
+	}
+
+	err = ef.setSheetFormats(f, sheetName, cols, fixedCellCount)
 	if err != nil {
 		return err
 	}
 
-	err = ef.addSparkLines(f, sheetName, 3)
+	err = ef.addSparkLines(f, sheetName, rowCount, fixedCellCount)
 	if err != nil {
 		return err
 	}
 
-	err2 := ef.addTable(f, sheetName, "CostSummary", len(costs), lastCol)
-	if err2 != nil {
-		return err2
+	err = ef.addTable(f, sheetName, tableName, rowCount, lastCol)
+	if err != nil {
+		return err
 	}
+
+	return f.SetPanes(sheetName, &excelize.Panes{
+		Freeze:      true,
+		XSplit:      1,
+		YSplit:      1,
+		TopLeftCell: "B2",
+		ActivePane:  "bottomRight",
+	})
+}
+
+// addTotalsRow adds a row below the table which totals each cost column. SUBTOTAL is used so that the totals follow
+// any filter applied to the table.
+func (ef ExcelFormatter) addTotalsRow(f *excelize.File, sheetName string, rowCount int, fixedCellCount int, lastTotalCol int) error {
+	customNumFmt := "#,##0.00;(#,##0.00);-"
+	totalRow := rowCount + 2
+
+	labelStyle, _ := f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Bold: true},
+		Border: []excelize.Border{{Type: "top", Color: "000000", Style: 1}, {Type: "bottom", Color: "000000", Style: 6}},
+	})
+	totalStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true},
+		Border:       []excelize.Border{{Type: "top", Color: "000000", Style: 1}, {Type: "bottom", Color: "000000", Style: 6}},
+		CustomNumFmt: &customNumFmt,
+		Alignment:    &excelize.Alignment{Horizontal: "right"},
+	})
+
+	labelCell, _ := excelize.JoinCellName(firstCol, totalRow)
+	if err := f.SetCellValue(sheetName, labelCell, "Total"); err != nil {
+		return fmt.Errorf("unable to add totals row to %s worksheet: %v", sheetName, err)
+	}
+
+	for i := fixedCellCount + 1; i <= lastTotalCol; i++ {
+		colName, _ := excelize.ColumnNumberToName(i)
+		cell, _ := excelize.JoinCellName(colName, totalRow)
+
+		// Set the total as the cached value of the formula for applications which do not calculate formulas
+		total := 0.0
+		for ri := 2; ri <= rowCount+1; ri++ {
+			valueCell, _ := excelize.JoinCellName(colName, ri)
+			value, _ := f.GetCellValue(sheetName, valueCell, excelize.Options{RawCellValue: true})
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				total += v
+			}
+		}
+		_ = f.SetCellFloat(sheetName, cell, total, 2, 64)
+
+		formula := fmt.Sprintf("SUBTOTAL(109,%s2:%s%d)", colName, colName, rowCount+1)
+		if err := f.SetCellFormula(sheetName, cell, formula); err != nil {
+			return fmt.Errorf("unable to add totals row to %s worksheet: %v", sheetName, err)
+		}
+	}
+
+	lastCell, _ := excelize.CoordinatesToCellName(lastTotalCol+1, totalRow)
+	fixedCell, _ := excelize.CoordinatesToCellName(fixedCellCount, totalRow)
+	firstTotalCell, _ := excelize.CoordinatesToCellName(fixedCellCount+1, totalRow)
+	_ = f.SetCellStyle(sheetName, labelCell, fixedCell, labelStyle)
+	_ = f.SetCellStyle(sheetName, firstTotalCell, lastCell, totalStyle)
+
+	return nil
+}
+
+// addSubscriptionChart adds a stacked column chart of the costs of each subscription for each billing period below the
+// summary table.
+func (ef ExcelFormatter) addSubscriptionChart(f *excelize.File, sheetName string, subscriptions []model.SubscriptionSummary) error {
+	sheetRef := quoteSheetName(sheetName)
+	lastPeriodCol, _ := excelize.ColumnNumberToName(len(subscriptions[0].Costs) + 1)
+
+	var series []excelize.ChartSeries
+	for i := range subscriptions {
+		row := i + 2
+		series = append(series, excelize.ChartSeries{
+			Name:       fmt.Sprintf("%s!$A$%d", sheetRef, row),
+			Categories: fmt.Sprintf("%s!$B$1:$%s$1", sheetRef, lastPeriodCol),
+			Values:     fmt.Sprintf("%s!$B$%d:$%s$%d", sheetRef, row, lastPeriodCol, row),
+		})
+	}
+
+	chartCell, _ := excelize.JoinCellName(firstCol, len(subscriptions)+4)
+	err := f.AddChart(sheetName, chartCell, &excelize.Chart{
+		Type:      excelize.ColStacked,
+		Series:    series,
+		Title:     []excelize.RichTextRun{{Text: fmt.Sprintf("Monthly costs by %s", strings.ToLower(ef.grouping.Parent))}},
+		Legend:    excelize.ChartLegend{Position: "right"},
+		Dimension: excelize.ChartDimension{Width: 800, Height: 400},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to add chart to %s worksheet: %v", sheetName, err)
+	}
+
 	return nil
 }
 
@@ -140,7 +334,7 @@ func (ef ExcelFormatter) addHeaders(f *excelize.File, sheetName string, billingP
 	firstCell, _ := excelize.JoinCellName("A", 1)
 
 	for _, bp := range billingPeriods {
-		headers = append(headers, bp.Period)
+		headers = append(headers, PeriodLabel(bp))
 	}
 
 	headers = append(headers, "Total Cost")
@@ -250,8 +444,7 @@ func (ef ExcelFormatter) setSheetFormats(f *excelize.File, sheetName string, col
 	return nil
 }
 
-func (ef ExcelFormatter) addSparkLines(f *excelize.File, sheetName string, fixedCellCount int) error {
-	rows, _ := f.GetRows(sheetName)
+func (ef ExcelFormatter) addSparkLines(f *excelize.File, sheetName string, rowCount int, fixedCellCount int) error {
 	cols, _ := f.GetCols(sheetName)
 	lastColumn, _ := excelize.ColumnNumberToName(len(cols))
 	startDataColumn, _ := excelize.ColumnNumberToName(fixedCellCount + 1)
@@ -259,19 +452,13 @@ func (ef ExcelFormatter) addSparkLines(f *excelize.File, sheetName string, fixed
 
 	var sparkLineLocation []string
 	var sparkLineRange []string
-	for i := range rows {
-		if i < 1 {
-			continue
-		}
-
-		ri := i + 1
-
+	for ri := 2; ri <= rowCount+1; ri++ {
 		location, _ := excelize.JoinCellName(lastColumn, ri)
 		start, _ := excelize.JoinCellName(startDataColumn, ri)
 		end, _ := excelize.JoinCellName(lastDataColumn, ri)
 
 		sparkLineLocation = append(sparkLineLocation, location)
-		sparkLineRange = append(sparkLineRange, fmt.Sprintf("%s!%s:%s", sheetName, start, end))
+		sparkLineRange = append(sparkLineRange, fmt.Sprintf("%s!%s:%s", quoteSheetName(sheetName), start, end))
 	}
 
 	return f.AddSparkline(sheetName, &excelize.SparklineOptions{
@@ -295,8 +482,145 @@ func (ef ExcelFormatter) addTable(f *excelize.File, sheetName string, tableName
 		ShowRowStripes: &showRowStripes,
 	})
 	if err != nil {
-		return fmt.Errorf("unable to add table to costs sheet: %v", err)
+		return fmt.Errorf("unable to add table to %s sheet: %v", sheetName, err)
 	}
 
 	return nil
 }
+
+// createDataSheet adds a sheet with a row for the cost of each resource group in each billing period.
+func (ef ExcelFormatter) createDataSheet(f *excelize.File) error {
+	_, err := f.NewSheet(dataSheetName)
+	if err != nil {
+		return err
+	}
+
+	headers := []string{"Subscription", "Resource Group", "Period", "Cost", "Cost (USD)", "Currency", "Active"}
+	err = f.SetSheetRow(dataSheetName, "A1", &headers)
+	if err != nil {
+		return fmt.Errorf("unable to set header row in data sheet: %v", err)
+	}
+
+	for i, record := range ef.data {
+		rowStart, _ := excelize.JoinCellName("A", i+2)
+		row := []interface{}{
+			record.SubscriptionName,
+			record.ResourceGroup,
+			record.BillingPeriod,
+			record.Cost,
+			record.CostUSD,
+			record.Currency,
+			record.Active,
+		}
+
+		err := f.SetSheetRow(dataSheetName, rowStart, &row)
+		if err != nil {
+			return fmt.Errorf("unable to add data row to data worksheet: %v", err)
+		}
+	}
+
+	customNumFmt := "#,##0.00;(#,##0.00);-"
+	costStyle, _ := f.NewStyle(&excelize.Style{CustomNumFmt: &customNumFmt})
+
+	_ = f.SetColWidth(dataSheetName, "A", "B", 40)
+	_ = f.SetColWidth(dataSheetName, "C", "G", 15)
+	_ = f.SetColStyle(dataSheetName, "D:E", costStyle)
+
+	err = ef.addTable(f, dataSheetName, "CostData", len(ef.data), "G")
+	if err != nil {
+		return err
+	}
+
+	return f.SetPanes(dataSheetName, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+}
+
+// createPivotSheet adds a pivot table over the data sheet, with the cost of each resource group by billing period. The
+// currency is added as a filter, as costs in different currencies cannot be added together. The workbook is refreshed
+// when opened, so the pivot table is populated by Excel rather than here.
+func (ef ExcelFormatter) createPivotSheet(f *excelize.File) error {
+	_, err := f.NewSheet(pivotSheetName)
+	if err != nil {
+		return err
+	}
+
+	var periods, subscriptions, resourceGroups []string
+	for _, record := range ef.data {
+		if !slices.Contains(periods, record.BillingPeriod) {
+			periods = append(periods, record.BillingPeriod)
+		}
+		if !slices.Contains(subscriptions, record.SubscriptionName) {
+			subscriptions = append(subscriptions, record.SubscriptionName)
+		}
+		if key := record.SubscriptionName + "/" + record.ResourceGroup; !slices.Contains(resourceGroups, key) {
+			resourceGroups = append(resourceGroups, key)
+		}
+	}
+
+	// The range of the pivot table is the space it will fill once refreshed, with a row for each subscription, resource
+	// group, and the headers and totals, and a column for each billing period.
+	lastCell, _ := excelize.CoordinatesToCellName(len(periods)+2, len(subscriptions)+len(resourceGroups)+5)
+
+	err = f.AddPivotTable(&excelize.PivotTableOptions{
+		DataRange:       fmt.Sprintf("%s!A1:G%d", dataSheetName, len(ef.data)+1),
+		PivotTableRange: fmt.Sprintf("%s!A3:%s", pivotSheetName, lastCell),
+		Rows: []excelize.PivotTableField{
+			{Data: "Subscription", DefaultSubtotal: true},
+			{Data: "Resource Group"},
+		},
+		Columns: []excelize.PivotTableField{
+			{Data: "Period"},
+		},
+		Data: []excelize.PivotTableField{
+			{Data: "Cost", Name: "Total Cost", Subtotal: "Sum"},
+		},
+		Filter: []excelize.PivotTableField{
+			{Data: "Currency"},
+		},
+		RowGrandTotals:      true,
+		ColGrandTotals:      true,
+		ShowDrill:           true,
+		ShowRowHeaders:      true,
+		ShowColHeaders:      true,
+		ShowLastColumn:      true,
+		PivotTableStyleName: "PivotStyleMedium9",
+	})
+	if err != nil {
+		return fmt.Errorf("unable to add pivot table: %v", err)
+	}
+
+	return nil
+}
+
+// uniqueSheetName returns a name for a sheet which Excel will accept, removing the characters which are not allowed in
+// sheet names and limiting it to 31 characters. A number is added to names which have already been used.
+func uniqueSheetName(name string, used []string) string {
+	name = strings.Trim(strings.NewReplacer(":", "", "\\", "", "/", "", "?", "", "*", "", "[", "", "]", "").Replace(name), "' ")
+	if len(name) == 0 {
+		name = "(Blank)"
+	}
+
+	candidate := truncateRunes(name, 31)
+	for i := 2; slices.ContainsFunc(used, func(u string) bool { return strings.EqualFold(u, candidate) }); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		candidate = truncateRunes(name, 31-len(suffix)) + suffix
+	}
+
+	return candidate
+}
+
+func truncateRunes(value string, length int) string {
+	if runes := []rune(value); len(runes) > length {
+		return string(runes[:length])
+	}
+	return value
+}
+
+// quoteSheetName quotes the sheet name for use in a cell reference.
+func quoteSheetName(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
@@ -0,0 +1,143 @@
+package formats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"github.com/parquet-go/parquet-go"
+	"io"
+	"time"
+)
+
+// focusRow is a single row of FOCUS data. As costs are collected per billing period, the charge period of each row
+// covers the whole billing period. Columns which are specific to Azure use the "x_" prefix in the same way as the
+// FOCUS exports from Cost Management.
+type focusRow struct {
+	BilledCost         float64   `parquet:"BilledCost"`
+	EffectiveCost      float64   `parquet:"EffectiveCost"`
+	BillingCurrency    string    `parquet:"BillingCurrency"`
+	BillingPeriodStart time.Time `parquet:"BillingPeriodStart,timestamp(millisecond)"`
+	BillingPeriodEnd   time.Time `parquet:"BillingPeriodEnd,timestamp(millisecond)"`
+	ChargePeriodStart  time.Time `parquet:"ChargePeriodStart,timestamp(millisecond)"`
+	ChargePeriodEnd    time.Time `parquet:"ChargePeriodEnd,timestamp(millisecond)"`
+	ChargeCategory     string    `parquet:"ChargeCategory"`
+	ProviderName       string    `parquet:"ProviderName"`
+	PublisherName      string    `parquet:"PublisherName"`
+	InvoiceIssuerName  string    `parquet:"InvoiceIssuerName"`
+	SubAccountId       string    `parquet:"SubAccountId"`
+	SubAccountName     string    `parquet:"SubAccountName"`
+	ResourceGroupName  string    `parquet:"x_ResourceGroupName"`
+}
+
+var focusHeader = []string{
+	"BilledCost",
+	"EffectiveCost",
+	"BillingCurrency",
+	"BillingPeriodStart",
+	"BillingPeriodEnd",
+	"ChargePeriodStart",
+	"ChargePeriodEnd",
+	"ChargeCategory",
+	"ProviderName",
+	"PublisherName",
+	"InvoiceIssuerName",
+	"SubAccountId",
+	"SubAccountName",
+	"x_ResourceGroupName",
+}
+
+// FocusFormatter outputs the costs of each resource group as long-form rows using the FinOps FOCUS column names, with
+// one row per resource group and billing period. Output is written as CSV, unless useParquet is set in which case
+// the rows are written as a Parquet file instead.
+type FocusFormatter struct {
+	useParquet bool
+}
+
+func MakeFocusFormatter(useParquet bool) FocusFormatter {
+	return FocusFormatter{useParquet: useParquet}
+}
+
+func (ff FocusFormatter) Generate(w io.Writer, costs []model.ResourceGroupSummary) error {
+	rows, err := focusRows(costs)
+	if err != nil {
+		return err
+	}
+
+	if ff.useParquet {
+		return parquet.Write(w, rows)
+	}
+
+	writer := csv.NewWriter(w)
+
+	err = writer.Write(focusHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		record := []string{
+			fmt.Sprintf("%.2f", row.BilledCost),
+			fmt.Sprintf("%.2f", row.EffectiveCost),
+			row.BillingCurrency,
+			row.BillingPeriodStart.Format(time.RFC3339),
+			row.BillingPeriodEnd.Format(time.RFC3339),
+			row.ChargePeriodStart.Format(time.RFC3339),
+			row.ChargePeriodEnd.Format(time.RFC3339),
+			row.ChargeCategory,
+			row.ProviderName,
+			row.PublisherName,
+			row.InvoiceIssuerName,
+			row.SubAccountId,
+			row.SubAccountName,
+			row.ResourceGroupName,
+		}
+
+		err := writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// focusRows converts the summaries into FOCUS rows. Forecast periods and periods without costs are not included as
+// FOCUS only describes incurred charges, and the effective cost is the same as the billed cost as amortized costs are
+// not collected.
+func focusRows(costs []model.ResourceGroupSummary) ([]focusRow, error) {
+	var rows []focusRow
+
+	for _, rg := range costs {
+		for _, bp := range rg.Costs {
+			if bp.Forecast || bp.Total == 0 {
+				continue
+			}
+
+			periodStart, err := time.Parse("2006-01", bp.Period)
+			if err != nil {
+				return nil, err
+			}
+			periodEnd := periodStart.AddDate(0, 1, 0)
+
+			rows = append(rows, focusRow{
+				BilledCost:         bp.Total,
+				EffectiveCost:      bp.Total,
+				BillingCurrency:    rg.Currency,
+				BillingPeriodStart: periodStart,
+				BillingPeriodEnd:   periodEnd,
+				ChargePeriodStart:  periodStart,
+				ChargePeriodEnd:    periodEnd,
+				ChargeCategory:     "Usage",
+				ProviderName:       "Microsoft",
+				PublisherName:      "Microsoft",
+				InvoiceIssuerName:  "Microsoft",
+				SubAccountId:       fmt.Sprintf("/subscriptions/%s", rg.SubscriptionId),
+				SubAccountName:     rg.SubscriptionName,
+				ResourceGroupName:  rg.Name,
+			})
+		}
+	}
+
+	return rows, nil
+}
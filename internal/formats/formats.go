@@ -1,34 +1,77 @@
 package formats
 
 import (
-	"errors"
-	"fmt"
 	"github.com/dazfuller/azcosts/internal/model"
-	"os"
+	"io"
+	"slices"
+	"strings"
 )
 
+// Grouping describes the level at which costs have been summarised, providing the labels used for the name of each
+// summary and of the parent it belongs to.
+type Grouping struct {
+	Name   string
+	Parent string
+}
+
+var (
+	ResourceGroupGrouping   = Grouping{Name: "Resource Group", Parent: "Subscription"}
+	ManagementGroupGrouping = Grouping{Name: "Management Group", Parent: "Parent Management Group"}
+	TenantGrouping          = Grouping{Name: "Subscription", Parent: "Tenant"}
+)
+
+// Formatter is implemented by the formats which summarise the costs of each billing period. The report is written to
+// w, which may be stdout or a file.
 type Formatter interface {
-	Generate(costs []model.ResourceGroupSummary) error
+	Generate(w io.Writer, costs []model.ResourceGroupSummary) error
 }
 
-func validateOptions(useStdOut bool, outputPath string) error {
-	if !useStdOut && len(outputPath) == 0 {
-		return fmt.Errorf("when writing to file and file path must be specified")
+// BudgetFormatter is implemented by formatters which are able to output budget utilisation reports.
+type BudgetFormatter interface {
+	GenerateBudgets(w io.Writer, utilisation []model.BudgetUtilisation) error
+}
+
+// PeriodLabel returns the column heading for a billing period, marking forecast periods so that they can be
+// distinguished from the actual costs. Forecasts collected from Azure are marked separately from those calculated
+// locally.
+func PeriodLabel(bp model.BillingPeriodCost) string {
+	if bp.Forecast && bp.Source == "azure" {
+		return bp.Period + " (AF)"
+	} else if bp.Forecast {
+		return bp.Period + " (F)"
 	}
+	return bp.Period
+}
 
-	if !useStdOut {
-		_, err := os.Stat(outputPath)
-		if !errors.Is(err, os.ErrNotExist) {
-			err := os.Remove(outputPath)
-			if err != nil {
-				return err
-			}
+// hasForecast returns true if any of the billing periods are forecasts.
+func hasForecast(billingPeriods []model.BillingPeriodCost) bool {
+	for _, bp := range billingPeriods {
+		if bp.Forecast {
+			return true
 		}
 	}
+	return false
+}
+
+// forecastLegend returns the legend explaining the forecast markers used in the labels of the billing periods, only
+// including the markers of the forecasts present. An empty string is returned when none of the periods are forecasts.
+func forecastLegend(billingPeriods []model.BillingPeriodCost) string {
+	var markers []string
+	if slices.ContainsFunc(billingPeriods, func(bp model.BillingPeriodCost) bool { return bp.Forecast && bp.Source != "azure" }) {
+		markers = append(markers, "(F) Forecast values")
+	}
+	if slices.ContainsFunc(billingPeriods, func(bp model.BillingPeriodCost) bool { return bp.Forecast && bp.Source == "azure" }) {
+		markers = append(markers, "(AF) Azure Cost Management forecast values")
+	}
 
-	return nil
+	if len(markers) == 0 {
+		return ""
+	}
+	return strings.Join(markers, ", ") + ", these are not included in the total costs"
 }
 
+// generateSubscriptionSummary rolls the costs up to the subscription, or other parent, of each summary, ordered by
+// name.
 func generateSubscriptionSummary(costs []model.ResourceGroupSummary) []model.SubscriptionSummary {
 	subscriptions := make(map[string]*model.SubscriptionSummary)
 
@@ -39,8 +82,10 @@ func generateSubscriptionSummary(costs []model.ResourceGroupSummary) []model.Sub
 
 			for i, cost := range cost.Costs {
 				subCosts[i] = model.BillingPeriodCost{
-					Period: cost.Period,
-					Total:  0,
+					Period:   cost.Period,
+					Total:    0,
+					Forecast: cost.Forecast,
+					Source:   cost.Source,
 				}
 			}
 
@@ -57,7 +102,9 @@ func generateSubscriptionSummary(costs []model.ResourceGroupSummary) []model.Sub
 
 		for i, bp := range cost.Costs {
 			subscription.Costs[i].Total += bp.Total
-			subscription.TotalCost += bp.Total
+			if !bp.Forecast {
+				subscription.TotalCost += bp.Total
+			}
 		}
 	}
 
@@ -66,5 +113,9 @@ func generateSubscriptionSummary(costs []model.ResourceGroupSummary) []model.Sub
 		subscriptionSummary = append(subscriptionSummary, *sub)
 	}
 
+	slices.SortFunc(subscriptionSummary, func(a, b model.SubscriptionSummary) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
 	return subscriptionSummary
 }
@@ -0,0 +1,309 @@
+package formats
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"html/template"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// topMoverCount is the number of resource groups shown in the top movers table of the HTML report.
+const topMoverCount = 10
+
+const (
+	chartWidth  = 360
+	chartHeight = 140
+	chartLabel  = 16
+)
+
+type htmlPeriod struct {
+	Label    string
+	Forecast bool
+}
+
+type htmlCost struct {
+	Value    float64
+	Forecast bool
+}
+
+type htmlSubscription struct {
+	Name      string
+	Costs     []htmlCost
+	TotalCost float64
+	Chart     template.HTML
+}
+
+type htmlMover struct {
+	Name     string
+	Parent   string
+	Previous float64
+	Latest   float64
+	Change   float64
+	Percent  string
+}
+
+type htmlReport struct {
+	Generated       string
+	Grouping        Grouping
+	Periods         []htmlPeriod
+	PeriodTotals    []htmlCost
+	TotalCost       float64
+	ForecastLegend  string
+	Subscriptions   []htmlSubscription
+	Costs           []model.ResourceGroupSummary
+	MoverFrom       string
+	MoverTo         string
+	Movers          []htmlMover
+	HasMoverPeriods bool
+}
+
+// HtmlFormatter outputs a single self-contained HTML page, with the styles, scripts, and charts inlined so that the
+// report can be sent by email or opened without network access.
+type HtmlFormatter struct {
+	grouping Grouping
+}
+
+func MakeHtmlFormatter(grouping Grouping) HtmlFormatter {
+	return HtmlFormatter{grouping: grouping}
+}
+
+func (hf HtmlFormatter) Generate(w io.Writer, costs []model.ResourceGroupSummary) error {
+	report := htmlReport{
+		Generated:      time.Now().UTC().Format("2006-01-02 15:04 MST"),
+		Grouping:       hf.grouping,
+		ForecastLegend: forecastLegend(costs[0].Costs),
+		Costs:          costs,
+	}
+
+	for _, bp := range costs[0].Costs {
+		report.Periods = append(report.Periods, htmlPeriod{Label: PeriodLabel(bp), Forecast: bp.Forecast})
+		report.PeriodTotals = append(report.PeriodTotals, htmlCost{Forecast: bp.Forecast})
+	}
+
+	for _, rg := range costs {
+		report.TotalCost += rg.TotalCost
+		for i, bp := range rg.Costs {
+			report.PeriodTotals[i].Value += bp.Total
+		}
+	}
+
+	subscriptions := generateSubscriptionSummary(costs)
+
+	for _, sub := range subscriptions {
+		subscription := htmlSubscription{Name: sub.Name, TotalCost: sub.TotalCost, Chart: trendChart(sub.Costs)}
+		for _, bp := range sub.Costs {
+			subscription.Costs = append(subscription.Costs, htmlCost{Value: bp.Total, Forecast: bp.Forecast})
+		}
+		report.Subscriptions = append(report.Subscriptions, subscription)
+	}
+
+	report.MoverFrom, report.MoverTo, report.Movers = topMovers(costs)
+	report.HasMoverPeriods = len(report.MoverTo) > 0
+
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"cost": func(value float64) string { return fmt.Sprintf("%.2f", value) },
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(w)
+
+	if err = tmpl.Execute(writer, report); err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// topMovers returns the resource groups whose costs changed the most between the last two billing periods which are
+// not forecasts, along with the labels of those periods.
+func topMovers(costs []model.ResourceGroupSummary) (string, string, []htmlMover) {
+	var actual []int
+	for i, bp := range costs[0].Costs {
+		if !bp.Forecast {
+			actual = append(actual, i)
+		}
+	}
+
+	if len(actual) < 2 {
+		return "", "", nil
+	}
+
+	from, to := actual[len(actual)-2], actual[len(actual)-1]
+
+	var movers []htmlMover
+	for _, rg := range costs {
+		previous, latest := rg.Costs[from].Total, rg.Costs[to].Total
+		if math.Abs(latest-previous) < 0.005 {
+			continue
+		}
+
+		percent := "new"
+		if previous != 0 {
+			percent = fmt.Sprintf("%+.1f%%", (latest-previous)/previous*100)
+		}
+
+		movers = append(movers, htmlMover{
+			Name:     rg.Name,
+			Parent:   rg.SubscriptionName,
+			Previous: previous,
+			Latest:   latest,
+			Change:   latest - previous,
+			Percent:  percent,
+		})
+	}
+
+	slices.SortStableFunc(movers, func(a, b htmlMover) int {
+		return cmp.Compare(math.Abs(b.Change), math.Abs(a.Change))
+	})
+
+	if len(movers) > topMoverCount {
+		movers = movers[:topMoverCount]
+	}
+
+	return costs[0].Costs[from].Period, costs[0].Costs[to].Period, movers
+}
+
+// trendChart returns an inline SVG bar chart of the costs in each billing period, with forecast periods drawn in a
+// lighter colour.
+func trendChart(costs []model.BillingPeriodCost) template.HTML {
+	maxCost := 0.0
+	for _, bp := range costs {
+		maxCost = math.Max(maxCost, bp.Total)
+	}
+
+	plotHeight := float64(chartHeight - chartLabel)
+	slot := float64(chartWidth) / float64(len(costs))
+	barWidth := slot * 0.7
+
+	svg := strings.Builder{}
+	svg.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img">`,
+		chartWidth, chartHeight, chartWidth, chartHeight))
+
+	for i, bp := range costs {
+		height := 0.0
+		if maxCost > 0 {
+			height = math.Max(bp.Total, 0) / maxCost * (plotHeight - 4)
+		}
+
+		class := "bar"
+		if bp.Forecast {
+			class = "bar forecast"
+		}
+
+		x := float64(i)*slot + (slot-barWidth)/2
+		svg.WriteString(fmt.Sprintf(`<rect class="%s" x="%.1f" y="%.1f" width="%.1f" height="%.1f"><title>%s: %.2f</title></rect>`,
+			class, x, plotHeight-height, barWidth, height, template.HTMLEscapeString(PeriodLabel(bp)), bp.Total))
+		svg.WriteString(fmt.Sprintf(`<text x="%.1f" y="%d" text-anchor="middle">%s</text>`,
+			float64(i)*slot+slot/2, chartHeight-4, template.HTMLEscapeString(bp.Period)))
+	}
+
+	svg.WriteString(fmt.Sprintf(`<line x1="0" y1="%.1f" x2="%d" y2="%.1f" class="axis"/>`, plotHeight, chartWidth, plotHeight))
+	svg.WriteString("</svg>")
+
+	return template.HTML(svg.String())
+}
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Azure costs summary</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; margin: 2em; }
+h1 { font-size: 1.6em; margin-bottom: 0.2em; }
+h2 { font-size: 1.2em; margin-top: 2em; border-bottom: 1px solid #ddd; padding-bottom: 0.2em; }
+p.meta { color: #666; margin-top: 0; }
+table { border-collapse: collapse; font-size: 0.9em; }
+th, td { padding: 4px 8px; border-bottom: 1px solid #eee; white-space: nowrap; }
+th { background: #f4f6f8; text-align: left; }
+td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+td.forecast, th.forecast { color: #777; font-style: italic; }
+tr.total td { font-weight: bold; border-top: 2px solid #ccc; }
+table.sortable th { cursor: pointer; user-select: none; }
+table.sortable th.asc::after { content: " \25B2"; }
+table.sortable th.desc::after { content: " \25BC"; }
+td.up { color: #b42318; }
+td.down { color: #067647; }
+.charts { display: flex; flex-wrap: wrap; gap: 1.5em; }
+.chart h3 { font-size: 1em; margin: 0 0 0.4em 0; }
+.chart svg .bar { fill: #2f6fde; }
+.chart svg .bar.forecast { fill: #a9c2f0; }
+.chart svg .axis { stroke: #999; }
+.chart svg text { font-size: 10px; fill: #555; }
+</style>
+</head>
+<body>
+<h1>Azure costs summary</h1>
+<p class="meta">Generated {{.Generated}}, total cost {{cost .TotalCost}}</p>
+
+<h2>Subscriptions</h2>
+<table>
+<thead>
+<tr><th>Subscription</th>{{range .Periods}}<th class="num{{if .Forecast}} forecast{{end}}">{{.Label}}</th>{{end}}<th class="num">Total Costs</th></tr>
+</thead>
+<tbody>
+{{range .Subscriptions}}<tr><td>{{.Name}}</td>{{range .Costs}}<td class="num{{if .Forecast}} forecast{{end}}">{{cost .Value}}</td>{{end}}<td class="num">{{cost .TotalCost}}</td></tr>
+{{end}}<tr class="total"><td>Total</td>{{range .PeriodTotals}}<td class="num{{if .Forecast}} forecast{{end}}">{{cost .Value}}</td>{{end}}<td class="num">{{cost .TotalCost}}</td></tr>
+</tbody>
+</table>
+
+<h2>Trends</h2>
+<div class="charts">
+{{range .Subscriptions}}<div class="chart"><h3>{{.Name}}</h3>{{.Chart}}</div>
+{{end}}</div>
+
+{{if .HasMoverPeriods}}<h2>Top movers, {{.MoverFrom}} to {{.MoverTo}}</h2>
+{{if .Movers}}<table>
+<thead>
+<tr><th>{{.Grouping.Name}}</th><th>{{.Grouping.Parent}}</th><th class="num">{{.MoverFrom}}</th><th class="num">{{.MoverTo}}</th><th class="num">Change</th><th class="num">Change %</th></tr>
+</thead>
+<tbody>
+{{range .Movers}}<tr><td>{{.Name}}</td><td>{{.Parent}}</td><td class="num">{{cost .Previous}}</td><td class="num">{{cost .Latest}}</td><td class="num {{if gt .Change 0.0}}up{{else}}down{{end}}">{{cost .Change}}</td><td class="num">{{.Percent}}</td></tr>
+{{end}}</tbody>
+</table>
+{{else}}<p>No costs changed between these periods.</p>
+{{end}}{{end}}
+<h2>{{.Grouping.Name}} costs</h2>
+<table class="sortable" id="costs">
+<thead>
+<tr><th>{{.Grouping.Name}}</th><th>{{.Grouping.Parent}}</th><th>Active</th>{{range .Periods}}<th class="num{{if .Forecast}} forecast{{end}}">{{.Label}}</th>{{end}}<th class="num">Total Costs</th></tr>
+</thead>
+<tbody>
+{{range .Costs}}<tr><td>{{.Name}}</td><td>{{.SubscriptionName}}</td><td>{{.Active}}</td>{{range .Costs}}<td class="num{{if .Forecast}} forecast{{end}}" data-value="{{.Total}}">{{cost .Total}}</td>{{end}}<td class="num" data-value="{{.TotalCost}}">{{cost .TotalCost}}</td></tr>
+{{end}}</tbody>
+</table>
+{{with .ForecastLegend}}<p class="meta">{{.}}</p>{{end}}
+
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  var headers = table.querySelectorAll("th");
+  headers.forEach(function (th, column) {
+    th.addEventListener("click", function () {
+      var ascending = !th.classList.contains("asc");
+      headers.forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(ascending ? "asc" : "desc");
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[column], y = b.cells[column];
+        var c = x.dataset.value !== undefined
+          ? parseFloat(x.dataset.value) - parseFloat(y.dataset.value)
+          : x.textContent.localeCompare(y.textContent);
+        return ascending ? c : -c;
+      });
+      rows.forEach(function (row) { body.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`
@@ -3,68 +3,78 @@ package formats
 import (
 	"encoding/json"
 	"github.com/dazfuller/azcosts/internal/model"
-	"os"
+	"io"
 	"time"
 )
 
 type report struct {
 	Generated          time.Time                    `json:"generated"`
+	GroupBy            string                       `json:"groupBy"`
 	ResourceGroupCount int                          `json:"resourceGroupCount"`
 	TotalCost          float64                      `json:"totalCost"`
 	Subscriptions      []model.SubscriptionSummary  `json:"subscriptions"`
 	ResourceGroups     []model.ResourceGroupSummary `json:"resourceGroups"`
 }
 
+type budgetReport struct {
+	Generated   time.Time                 `json:"generated"`
+	BreachCount int                       `json:"breachCount"`
+	Budgets     []model.BudgetUtilisation `json:"budgets"`
+}
+
 type JsonFormatter struct {
-	useStdOut  bool
-	outputPath string
+	grouping Grouping
 }
 
-func MakeJsonFormatter(useStdOut bool, outputPath string) (JsonFormatter, error) {
-	if err := validateOptions(useStdOut, outputPath); err != nil {
-		return JsonFormatter{}, err
-	}
+func MakeJsonFormatter(grouping Grouping) JsonFormatter {
+	return JsonFormatter{grouping: grouping}
+}
 
-	return JsonFormatter{useStdOut: useStdOut, outputPath: outputPath}, nil
+func (jf JsonFormatter) Generate(w io.Writer, costs []model.ResourceGroupSummary) error {
+	return jf.write(w, newReport(costs, jf.grouping))
 }
 
-func (jf JsonFormatter) Generate(costs []model.ResourceGroupSummary) error {
+// WriteJsonReport writes the same report as the JsonFormatter to w, without indentation, so that the report can be
+// written to outputs other than files such as HTTP responses.
+func WriteJsonReport(w io.Writer, costs []model.ResourceGroupSummary, grouping Grouping) error {
+	return json.NewEncoder(w).Encode(newReport(costs, grouping))
+}
+
+func newReport(costs []model.ResourceGroupSummary, grouping Grouping) report {
 	totalCost := float64(0)
 	for i := range costs {
 		totalCost += costs[i].TotalCost
 	}
 
-	subscriptionSummary := generateSubscriptionSummary(costs)
-
-	report := report{
+	return report{
 		Generated:          time.Now().UTC(),
+		GroupBy:            grouping.Name,
 		ResourceGroupCount: len(costs),
 		TotalCost:          totalCost,
-		Subscriptions:      subscriptionSummary,
+		Subscriptions:      generateSubscriptionSummary(costs),
 		ResourceGroups:     costs,
 	}
+}
 
-	if jf.useStdOut {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		if err := enc.Encode(report); err != nil {
-			return err
+func (jf JsonFormatter) GenerateBudgets(w io.Writer, utilisation []model.BudgetUtilisation) error {
+	breachCount := 0
+	for _, bu := range utilisation {
+		if bu.Breached {
+			breachCount++
 		}
-
-		return nil
 	}
 
-	file, err := os.Create(jf.outputPath)
-	if err != nil {
-		return err
+	report := budgetReport{
+		Generated:   time.Now().UTC(),
+		BreachCount: breachCount,
+		Budgets:     utilisation,
 	}
-	defer file.Close()
 
-	enc := json.NewEncoder(file)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(report); err != nil {
-		return err
-	}
+	return jf.write(w, report)
+}
 
-	return nil
+func (jf JsonFormatter) write(w io.Writer, value any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
 }
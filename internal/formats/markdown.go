@@ -0,0 +1,119 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"io"
+	"strings"
+)
+
+// MarkdownFormatter outputs GitHub-flavoured markdown tables which can be pasted into wiki pages or pull request
+// comments. When collapsible is set the costs of each subscription are written to their own collapsible section
+// rather than a single table.
+type MarkdownFormatter struct {
+	grouping    Grouping
+	collapsible bool
+}
+
+func MakeMarkdownFormatter(grouping Grouping, collapsible bool) MarkdownFormatter {
+	return MarkdownFormatter{grouping: grouping, collapsible: collapsible}
+}
+
+func (mf MarkdownFormatter) Generate(w io.Writer, costs []model.ResourceGroupSummary) error {
+	writer := bufio.NewWriter(w)
+
+	subscriptions := generateSubscriptionSummary(costs)
+
+	writer.WriteString(fmt.Sprintf("## %s summary\n\n", mf.grouping.Parent))
+	mf.writeHeader(writer, []string{mf.grouping.Parent}, costs[0].Costs)
+
+	totals := make([]model.BillingPeriodCost, len(costs[0].Costs))
+	totalCost := float64(0)
+	for _, sub := range subscriptions {
+		mf.writeRow(writer, []string{sub.Name}, sub.Costs, sub.TotalCost)
+		for i, bp := range sub.Costs {
+			totals[i].Total += bp.Total
+		}
+		totalCost += sub.TotalCost
+	}
+	mf.writeTotals(writer, 1, totals, totalCost)
+
+	writer.WriteString(fmt.Sprintf("\n## %s costs\n\n", mf.grouping.Name))
+
+	if mf.collapsible {
+		for _, sub := range subscriptions {
+			writer.WriteString(fmt.Sprintf("<details>\n<summary>%s (%.2f)</summary>\n\n", markdownEscape(sub.Name), sub.TotalCost))
+			mf.writeHeader(writer, []string{mf.grouping.Name, "Active"}, costs[0].Costs)
+			for _, rg := range costs {
+				if rg.SubscriptionName == sub.Name {
+					mf.writeRow(writer, []string{rg.Name, fmt.Sprint(rg.Active)}, rg.Costs, rg.TotalCost)
+				}
+			}
+			mf.writeTotals(writer, 2, sub.Costs, sub.TotalCost)
+			writer.WriteString("\n</details>\n\n")
+		}
+	} else {
+		mf.writeHeader(writer, []string{mf.grouping.Name, mf.grouping.Parent, "Active"}, costs[0].Costs)
+		for _, rg := range costs {
+			mf.writeRow(writer, []string{rg.Name, rg.SubscriptionName, fmt.Sprint(rg.Active)}, rg.Costs, rg.TotalCost)
+		}
+		mf.writeTotals(writer, 3, totals, totalCost)
+		writer.WriteString("\n")
+	}
+
+	if legend := forecastLegend(costs[0].Costs); len(legend) > 0 {
+		writer.WriteString(fmt.Sprintf("_%s_\n", legend))
+	}
+
+	return writer.Flush()
+}
+
+func (mf MarkdownFormatter) writeHeader(writer *bufio.Writer, columns []string, billingPeriods []model.BillingPeriodCost) {
+	header := strings.Builder{}
+	separator := strings.Builder{}
+
+	header.WriteString("|")
+	separator.WriteString("|")
+	for _, column := range columns {
+		header.WriteString(fmt.Sprintf(" %s |", column))
+		separator.WriteString(" --- |")
+	}
+	for _, bp := range billingPeriods {
+		header.WriteString(fmt.Sprintf(" %s |", PeriodLabel(bp)))
+		separator.WriteString(" ---: |")
+	}
+	header.WriteString(" Total Costs |\n")
+	separator.WriteString(" ---: |\n")
+
+	writer.WriteString(header.String())
+	writer.WriteString(separator.String())
+}
+
+func (mf MarkdownFormatter) writeRow(writer *bufio.Writer, values []string, billingPeriods []model.BillingPeriodCost, totalCost float64) {
+	writer.WriteString("|")
+	for _, value := range values {
+		writer.WriteString(fmt.Sprintf(" %s |", markdownEscape(value)))
+	}
+	for _, bp := range billingPeriods {
+		writer.WriteString(fmt.Sprintf(" %.2f |", bp.Total))
+	}
+	writer.WriteString(fmt.Sprintf(" %.2f |\n", totalCost))
+}
+
+// writeTotals writes the totals row of a table, in bold so that it stands out from the rows above it. The label is
+// written to the first column and the remaining columns before the billing periods are left empty.
+func (mf MarkdownFormatter) writeTotals(writer *bufio.Writer, columns int, billingPeriods []model.BillingPeriodCost, totalCost float64) {
+	writer.WriteString("| **Total** |")
+	writer.WriteString(strings.Repeat(" |", columns-1))
+	for _, bp := range billingPeriods {
+		writer.WriteString(fmt.Sprintf(" **%.2f** |", bp.Total))
+	}
+
+	writer.WriteString(fmt.Sprintf(" **%.2f** |\n", totalCost))
+}
+
+// markdownEscape escapes the characters which would otherwise break the layout of a table or be rendered as markdown.
+func markdownEscape(value string) string {
+	return strings.NewReplacer("|", "\\|", "*", "\\*", "_", "\\_", "`", "\\`", "<", "&lt;", ">", "&gt;").Replace(value)
+}
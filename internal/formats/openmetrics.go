@@ -0,0 +1,72 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// OpenMetricsContentType is the content type of the OpenMetrics text exposition format.
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// OpenMetricsWriter writes each record as gauges in the OpenMetrics text exposition format, which can be scraped by
+// Prometheus or read by the node_exporter textfile collector. As the samples of each metric family must be written
+// together, the samples of the USD costs are held until the writer is closed.
+type OpenMetricsWriter struct {
+	writer *bufio.Writer
+	usd    strings.Builder
+	count  int
+}
+
+// NewOpenMetricsWriter returns a writer which writes to w, such as the response to a scrape request. Closing the
+// writer does not close w.
+func NewOpenMetricsWriter(w io.Writer) *OpenMetricsWriter {
+	return &OpenMetricsWriter{writer: bufio.NewWriter(w)}
+}
+
+func (ow *OpenMetricsWriter) Write(record model.CostRecord) error {
+	if ow.count == 0 {
+		ow.writer.WriteString("# TYPE azcosts_resource_group_cost gauge\n")
+		ow.writer.WriteString("# HELP azcosts_resource_group_cost The cost of the resource group for the billing period in the billing currency.\n")
+	}
+	ow.count++
+
+	labels := metricLabels(record)
+	ow.usd.WriteString(fmt.Sprintf("azcosts_resource_group_cost_usd{%s} %s\n", labels, metricValue(record.CostUSD)))
+
+	_, err := ow.writer.WriteString(fmt.Sprintf("azcosts_resource_group_cost{%s,currency=\"%s\"} %s\n",
+		labels, escapeLabelValue(record.Currency), metricValue(record.Cost)))
+	return err
+}
+
+func (ow *OpenMetricsWriter) Close() error {
+	if ow.count > 0 {
+		ow.writer.WriteString("# TYPE azcosts_resource_group_cost_usd gauge\n")
+		ow.writer.WriteString("# HELP azcosts_resource_group_cost_usd The cost of the resource group for the billing period in USD.\n")
+		ow.writer.WriteString(ow.usd.String())
+	}
+	ow.writer.WriteString("# EOF\n")
+
+	return ow.writer.Flush()
+}
+
+func metricLabels(record model.CostRecord) string {
+	return fmt.Sprintf(`subscription="%s",subscription_id="%s",tenant_id="%s",resource_group="%s",period="%s"`,
+		escapeLabelValue(record.SubscriptionName),
+		escapeLabelValue(record.SubscriptionId),
+		escapeLabelValue(record.TenantId),
+		escapeLabelValue(record.ResourceGroup),
+		escapeLabelValue(record.BillingPeriod))
+}
+
+func metricValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// escapeLabelValue escapes the backslashes, double quotes, and line feeds in a label value.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,123 @@
+package formats
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Output is where a report is written, either stdout or a file. Files are written to a temporary file in the same
+// directory, which only replaces the file at the output path once the report has been written in full and Commit is
+// called, so that a report which fails part way through does not destroy the previous report. Where the output path
+// is a symlink the file it links to is replaced, and the temporary file is written alongside that file instead.
+type Output struct {
+	file *os.File
+	path string
+	// replace is set when there is an existing file at the output path, in which case mode is its permissions.
+	replace bool
+	mode    fs.FileMode
+	done    bool
+}
+
+// CreateOutput returns an output which writes to stdout, or to the output path when not writing to stdout. Close must
+// be called once the output is no longer needed, which removes the temporary file if the output was not committed.
+func CreateOutput(useStdOut bool, outputPath string) (*Output, error) {
+	if useStdOut {
+		return &Output{file: os.Stdout}, nil
+	}
+
+	if len(outputPath) == 0 {
+		return nil, fmt.Errorf("when writing to file a file path must be specified")
+	}
+
+	// Reports written to a symlink replace the file it links to, rather than the link itself
+	if resolvedPath, err := filepath.EvalSymlinks(outputPath); err == nil {
+		outputPath = resolvedPath
+	}
+
+	// Replaced files keep their permissions, while new files are created with the permissions of any other new file
+	output := &Output{path: outputPath, mode: 0666}
+	if info, err := os.Stat(outputPath); err == nil && info.Mode().IsRegular() {
+		output.replace = true
+		output.mode = info.Mode().Perm()
+	}
+
+	file, err := createTemp(outputPath, output.mode)
+	if err != nil {
+		return nil, err
+	}
+	output.file = file
+
+	return output, nil
+}
+
+// createTemp creates a uniquely named hidden file in the same directory as the output path with the given permissions,
+// less the umask of the process. Unlike os.CreateTemp, which always uses 0600, the file has the same permissions as
+// the output file would have if it was created directly.
+func createTemp(outputPath string, mode fs.FileMode) (*os.File, error) {
+	for attempt := 0; ; attempt++ {
+		name := filepath.Join(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+"."+strconv.FormatUint(uint64(rand.Uint32()), 10)+".tmp")
+
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, mode)
+		if errors.Is(err, fs.ErrExist) && attempt < 10000 {
+			continue
+		}
+		return file, err
+	}
+}
+
+func (o *Output) Write(p []byte) (int, error) {
+	return o.file.Write(p)
+}
+
+// Fd returns the file descriptor of the output, so that formatters can determine if they are writing to a terminal.
+func (o *Output) Fd() uintptr {
+	return o.file.Fd()
+}
+
+// Commit replaces the file at the output path with the report written. It has no effect when writing to stdout.
+func (o *Output) Commit() error {
+	if len(o.path) == 0 || o.done {
+		return nil
+	}
+
+	if err := o.file.Close(); err != nil {
+		return err
+	}
+
+	// The umask may have removed permissions from the temporary file which the file being replaced has
+	if o.replace {
+		if err := os.Chmod(o.file.Name(), o.mode); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(o.file.Name(), o.path); err != nil {
+		return err
+	}
+
+	o.done = true
+	return nil
+}
+
+// Close removes the temporary file of an output which has not been committed, leaving any existing file at the output
+// path in place.
+func (o *Output) Close() error {
+	if len(o.path) == 0 || o.done {
+		return nil
+	}
+
+	o.done = true
+	err := o.file.Close()
+	if removeErr := os.Remove(o.file.Name()); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+		return removeErr
+	}
+	if errors.Is(err, os.ErrClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package formats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeOutput writes the content to the output path, committing it if requested.
+func writeOutput(t *testing.T, outputPath string, content string, commit bool) {
+	t.Helper()
+
+	output, err := CreateOutput(false, outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.Close()
+
+	if _, err := output.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if commit {
+		if err := output.Commit(); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+	}
+}
+
+// assertFiles checks that the directory contains only the named files, so that no temporary files are left behind.
+func assertFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(names) {
+		t.Fatalf("%s contains %d files, want %v", dir, len(entries), names)
+	}
+	for i, entry := range entries {
+		if entry.Name() != names[i] {
+			t.Errorf("%s contains %s, want %v", dir, entry.Name(), names)
+		}
+	}
+}
+
+func assertContent(t *testing.T, path string, want string) {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != want {
+		t.Errorf("%s contains %q, want %q", path, content, want)
+	}
+}
+
+func TestOutputCommit(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "report.csv")
+
+	writeOutput(t, outputPath, "first", true)
+	assertContent(t, outputPath, "first")
+
+	if err := os.Chmod(outputPath, 0604); err != nil {
+		t.Fatal(err)
+	}
+
+	writeOutput(t, outputPath, "second", true)
+	assertContent(t, outputPath, "second")
+	assertFiles(t, dir, "report.csv")
+
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0604 {
+		t.Errorf("permissions = %v, want %v", info.Mode().Perm(), os.FileMode(0604))
+	}
+}
+
+func TestOutputWithoutCommit(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "report.csv")
+
+	writeOutput(t, outputPath, "first", true)
+	writeOutput(t, outputPath, "partial", false)
+
+	assertContent(t, outputPath, "first")
+	assertFiles(t, dir, "report.csv")
+}
+
+func TestOutputToSymlink(t *testing.T) {
+	dir := t.TempDir()
+	targetDir := filepath.Join(dir, "reports")
+	if err := os.Mkdir(targetDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	targetPath := filepath.Join(targetDir, "report.csv")
+	if err := os.WriteFile(targetPath, []byte("first"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(targetPath, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	linkPath := filepath.Join(dir, "latest.csv")
+	if err := os.Symlink(filepath.Join("reports", "report.csv"), linkPath); err != nil {
+		t.Skipf("unable to create symlink: %v", err)
+	}
+
+	writeOutput(t, linkPath, "second", true)
+
+	info, err := os.Lstat(linkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("%s was replaced, want it to remain a symlink", linkPath)
+	}
+
+	assertContent(t, targetPath, "second")
+	assertFiles(t, dir, "latest.csv", "reports")
+	assertFiles(t, targetDir, "report.csv")
+
+	info, err = os.Stat(targetPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("permissions = %v, want %v", info.Mode().Perm(), os.FileMode(0640))
+	}
+}
@@ -0,0 +1,60 @@
+package formats
+
+import (
+	"github.com/dazfuller/azcosts/internal/model"
+	"github.com/parquet-go/parquet-go"
+	"io"
+	"math"
+	"time"
+)
+
+// parquetRecord is the typed schema of the Parquet output. The billing period is stored as the date of the first day
+// of the period, and costs are stored as decimals with a scale of 6.
+type parquetRecord struct {
+	SubscriptionId   string `parquet:"SubscriptionId"`
+	SubscriptionName string `parquet:"SubscriptionName"`
+	TenantId         string `parquet:"TenantId,optional"`
+	ResourceGroup    string `parquet:"ResourceGroup"`
+	BillingPeriod    int32  `parquet:"BillingPeriod,date"`
+	Cost             int64  `parquet:"Cost,decimal(6:18)"`
+	CostUSD          int64  `parquet:"CostUSD,decimal(6:18)"`
+	Currency         string `parquet:"Currency"`
+	Active           bool   `parquet:"Active"`
+}
+
+const parquetCostScale = 1e6
+
+// ParquetWriter writes each record as a row of a Parquet file, preserving the types of the values which are lost when
+// written as CSV.
+type ParquetWriter struct {
+	writer *parquet.GenericWriter[parquetRecord]
+}
+
+func NewParquetWriter(w io.Writer) *ParquetWriter {
+	return &ParquetWriter{writer: parquet.NewGenericWriter[parquetRecord](w)}
+}
+
+func (pw *ParquetWriter) Write(record model.CostRecord) error {
+	period, err := time.Parse("2006-01", record.BillingPeriod)
+	if err != nil {
+		return err
+	}
+
+	_, err = pw.writer.Write([]parquetRecord{{
+		SubscriptionId:   record.SubscriptionId,
+		SubscriptionName: record.SubscriptionName,
+		TenantId:         record.TenantId,
+		ResourceGroup:    record.ResourceGroup,
+		BillingPeriod:    int32(period.Unix() / (24 * 60 * 60)),
+		Cost:             int64(math.Round(record.Cost * parquetCostScale)),
+		CostUSD:          int64(math.Round(record.CostUSD * parquetCostScale)),
+		Currency:         record.Currency,
+		Active:           record.Active,
+	}})
+	return err
+}
+
+// Close writes the footer of the Parquet file, it does not close the underlying writer.
+func (pw *ParquetWriter) Close() error {
+	return pw.writer.Close()
+}
@@ -0,0 +1,86 @@
+package formats
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	"io"
+	"strconv"
+)
+
+// RecordWriter is implemented by the long-form formats, which write one record per resource group and billing period
+// as the records are read from the store. Close must be called once all records have been written, and flushes the
+// records to the underlying writer without closing it.
+type RecordWriter interface {
+	Write(record model.CostRecord) error
+	Close() error
+}
+
+var longCsvHeader = []string{
+	"SubscriptionId",
+	"SubscriptionName",
+	"TenantId",
+	"ResourceGroup",
+	"BillingPeriod",
+	"Cost",
+	"CostUSD",
+	"Currency",
+	"Active",
+}
+
+// LongCsvWriter writes each record as a row of a CSV file with a fixed header, unlike the CsvFormatter whose columns
+// change with the billing periods reported on.
+type LongCsvWriter struct {
+	writer *csv.Writer
+}
+
+func NewLongCsvWriter(w io.Writer) *LongCsvWriter {
+	writer := csv.NewWriter(w)
+
+	// Any error writing the header is returned when the writer is closed
+	_ = writer.Write(longCsvHeader)
+
+	return &LongCsvWriter{writer: writer}
+}
+
+func (lw *LongCsvWriter) Write(record model.CostRecord) error {
+	return lw.writer.Write([]string{
+		record.SubscriptionId,
+		record.SubscriptionName,
+		record.TenantId,
+		record.ResourceGroup,
+		record.BillingPeriod,
+		fmt.Sprintf("%.2f", record.Cost),
+		fmt.Sprintf("%.2f", record.CostUSD),
+		record.Currency,
+		strconv.FormatBool(record.Active),
+	})
+}
+
+// Close flushes the records written, it does not close the underlying writer.
+func (lw *LongCsvWriter) Close() error {
+	lw.writer.Flush()
+	return lw.writer.Error()
+}
+
+// NdjsonWriter writes each record as a JSON object on its own line.
+type NdjsonWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func NewNdjsonWriter(w io.Writer) *NdjsonWriter {
+	buffer := bufio.NewWriter(w)
+	return &NdjsonWriter{buffer: buffer, encoder: json.NewEncoder(buffer)}
+}
+
+func (nw *NdjsonWriter) Write(record model.CostRecord) error {
+	return nw.encoder.Encode(record)
+}
+
+// Close flushes the records written, it does not close the underlying writer.
+func (nw *NdjsonWriter) Close() error {
+	return nw.buffer.Flush()
+}
@@ -0,0 +1,180 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/dazfuller/azcosts/internal/model"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// TemplateReport is the model passed to user-defined templates.
+type TemplateReport struct {
+	// Generated is the time at which the report was generated, in UTC.
+	Generated time.Time
+	// Grouping holds the labels for the level at which the costs have been summarised.
+	Grouping Grouping
+	// Periods are the billing periods reported on, with the total cost of each period.
+	Periods []model.BillingPeriodCost
+	// Subscriptions are the costs of each subscription, or the parent of each summary, ordered by name.
+	Subscriptions []model.SubscriptionSummary
+	// ResourceGroups are the costs of each resource group, or of each summary at the selected level.
+	ResourceGroups []model.ResourceGroupSummary
+	// TotalCost is the total of the costs, excluding forecasts.
+	TotalCost float64
+	// Currency is the currency of the costs, or empty where the costs are in more than one currency.
+	Currency string
+	// HasForecast is true if any of the periods are forecasts.
+	HasForecast bool
+}
+
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// TemplateFormatter renders the report using a user-supplied Go template. Templates with a .html or .htm extension are
+// parsed using html/template so that values are escaped, and all other templates using text/template.
+type TemplateFormatter struct {
+	grouping Grouping
+	template executor
+}
+
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"GBP": "£",
+	"EUR": "€",
+	"JPY": "¥",
+	"INR": "₹",
+	"AUD": "A$",
+	"CAD": "C$",
+	"NZD": "NZ$",
+}
+
+// templateFuncs are the helper functions available to templates, in addition to the standard template functions.
+var templateFuncs = map[string]any{
+	"cost":      func(value float64) string { return fmt.Sprintf("%.2f", value) },
+	"thousands": thousands,
+	"currency":  currencyValue,
+	"label":     PeriodLabel,
+	"padLeft": func(width int, value any) string {
+		s := fmt.Sprint(value)
+		return strings.Repeat(" ", max(width-utf8.RuneCountInString(s), 0)) + s
+	},
+	"padRight": func(width int, value any) string {
+		s := fmt.Sprint(value)
+		return s + strings.Repeat(" ", max(width-utf8.RuneCountInString(s), 0))
+	},
+	"truncate": func(width int, value string) string {
+		if utf8.RuneCountInString(value) > width {
+			return string([]rune(value)[:width])
+		}
+		return value
+	},
+	"repeat": func(count int, value string) string { return strings.Repeat(value, max(count, 0)) },
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+}
+
+func MakeTemplateFormatter(grouping Grouping, templatePath string) (TemplateFormatter, error) {
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		return TemplateFormatter{}, fmt.Errorf("unable to read template: %s", err.Error())
+	}
+
+	var tmpl executor
+	name := filepath.Base(templatePath)
+
+	switch strings.ToLower(filepath.Ext(templatePath)) {
+	case ".html", ".htm":
+		tmpl, err = htmltemplate.New(name).Funcs(templateFuncs).Parse(string(content))
+	default:
+		tmpl, err = template.New(name).Funcs(templateFuncs).Parse(string(content))
+	}
+	if err != nil {
+		return TemplateFormatter{}, fmt.Errorf("unable to parse template: %s", err.Error())
+	}
+
+	return TemplateFormatter{grouping: grouping, template: tmpl}, nil
+}
+
+func (tf TemplateFormatter) Generate(w io.Writer, costs []model.ResourceGroupSummary) error {
+	report := TemplateReport{
+		Generated:      time.Now().UTC(),
+		Grouping:       tf.grouping,
+		ResourceGroups: costs,
+		HasForecast:    hasForecast(costs[0].Costs),
+	}
+
+	for _, bp := range costs[0].Costs {
+		report.Periods = append(report.Periods, model.BillingPeriodCost{Period: bp.Period, Forecast: bp.Forecast, Source: bp.Source})
+	}
+
+	currencies := make(map[string]bool)
+	for _, rg := range costs {
+		report.TotalCost += rg.TotalCost
+		for i, bp := range rg.Costs {
+			report.Periods[i].Total += bp.Total
+		}
+		if len(rg.Currency) > 0 {
+			currencies[rg.Currency] = true
+		}
+	}
+
+	if len(currencies) == 1 {
+		for currency := range currencies {
+			report.Currency = currency
+		}
+	}
+
+	report.Subscriptions = generateSubscriptionSummary(costs)
+
+	writer := bufio.NewWriter(w)
+
+	if err := tf.template.Execute(writer, report); err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// thousands formats the value to 2 decimal places with a comma separating each group of thousands.
+func thousands(value float64) string {
+	s := fmt.Sprintf("%.2f", math.Abs(value))
+	whole, fraction, _ := strings.Cut(s, ".")
+
+	grouped := strings.Builder{}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteRune(',')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	sign := ""
+	if value < 0 && s != "0.00" {
+		sign = "-"
+	}
+
+	return sign + grouped.String() + "." + fraction
+}
+
+// currencyValue formats the value using the symbol of the currency where it is known, or with the currency code
+// following the value where it is not.
+func currencyValue(value float64, currency string) string {
+	if symbol, ok := currencySymbols[strings.ToUpper(currency)]; ok {
+		if value < 0 {
+			return "-" + symbol + thousands(-value)
+		}
+		return symbol + thousands(value)
+	} else if len(currency) > 0 {
+		return thousands(value) + " " + currency
+	}
+	return thousands(value)
+}
@@ -2,64 +2,235 @@ package formats
 
 import (
 	"bufio"
+	"cmp"
 	"fmt"
 	"github.com/dazfuller/azcosts/internal/model"
+	"golang.org/x/term"
+	"io"
 	"os"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
+// SortOrder is the order in which the rows of the text output are written.
+type SortOrder string
+
+const (
+	SortByName   SortOrder = "name"
+	SortByTotal  SortOrder = "total"
+	SortByLatest SortOrder = "latest"
+)
+
+const (
+	minNameWidth   = 20
+	minParentWidth = 12
+	colourIncrease = "\x1b[31m"
+	colourDecrease = "\x1b[32m"
+	colourBold     = "\x1b[1m"
+	colourReset    = "\x1b[0m"
+)
+
+// TextFormatter writes the costs as a table, with a subtotal for each subscription and a grand total. When writing to
+// a terminal the columns are sized to fit its width, and costs which have increased or decreased since the previous
+// billing period are coloured unless NO_COLOR is set.
 type TextFormatter struct {
-	useStdOut  bool
-	outputPath string
+	grouping  Grouping
+	sortOrder SortOrder
+	width     int
+	colour    bool
+}
+
+func MakeTextFormatter(grouping Grouping, sortOrder SortOrder) TextFormatter {
+	return TextFormatter{grouping: grouping, sortOrder: sortOrder}
 }
 
-func MakeTextFormatter(useStdOut bool, outputPath string) (TextFormatter, error) {
-	if err := validateOptions(useStdOut, outputPath); err != nil {
-		return TextFormatter{}, err
+func (tf TextFormatter) Generate(w io.Writer, costs []model.ResourceGroupSummary) error {
+	// The width and colour are only used when writing to a terminal
+	if f, ok := w.(interface{ Fd() uintptr }); ok && term.IsTerminal(int(f.Fd())) {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil {
+			tf.width = width
+		}
+		tf.colour = len(os.Getenv("NO_COLOR")) == 0
 	}
 
-	return TextFormatter{useStdOut: useStdOut, outputPath: outputPath}, nil
-}
+	writer := bufio.NewWriter(w)
 
-func (tf TextFormatter) Generate(costs []model.ResourceGroupSummary) error {
-	var writer *bufio.Writer
+	periods := costs[0].Costs
+	latest := latestPeriod(periods)
+	nameWidth, parentWidth := tf.columnWidths(costs)
 
-	if tf.useStdOut {
-		writer = bufio.NewWriter(os.Stdout)
-	} else {
-		file, err := os.Create(tf.outputPath)
-		if err != nil {
-			return err
-		}
-		writer = bufio.NewWriter(file)
+	writer.WriteString(fmt.Sprintf("%s %s %-7s", padText(tf.grouping.Name, nameWidth), padText(tf.grouping.Parent, parentWidth), "Active"))
+	for _, bp := range periods {
+		writer.WriteString(fmt.Sprintf(" %12s", PeriodLabel(bp)))
 	}
+	writer.WriteString(fmt.Sprintf(" %12s\n", "Total Costs"))
 
-	writer.WriteString(fmt.Sprintf("%-70s %-30s %-7s", "Resource Group", "Subscription", "Active"))
+	separator := func(char string) {
+		writer.WriteString(fmt.Sprintf("%s %s %s", strings.Repeat(char, nameWidth), strings.Repeat(char, parentWidth), strings.Repeat(char, 7)))
+		for range periods {
+			writer.WriteString(" " + strings.Repeat(char, 12))
+		}
+		writer.WriteString(" " + strings.Repeat(char, 12) + "\n")
+	}
 
-	for _, bp := range costs[0].Costs {
-		writer.WriteString(fmt.Sprintf(" %12s", bp.Period))
+	totals := func(label string, bpCosts []model.BillingPeriodCost, total float64) {
+		line := fmt.Sprintf("%s %s %-7s", padText(label, nameWidth), strings.Repeat(" ", parentWidth), "")
+		for _, bp := range bpCosts {
+			line += fmt.Sprintf(" %12.2f", bp.Total)
+		}
+		line += fmt.Sprintf(" %12.2f", total)
+		writer.WriteString(tf.colourise(line, colourBold) + "\n")
 	}
 
-	writer.WriteString(fmt.Sprintf(" %12s\n", "Total Costs"))
+	separator("=")
+
+	subscriptions := generateSubscriptionSummary(costs)
+	sortSubscriptions(subscriptions, tf.sortOrder, latest)
+
+	grandTotal := model.SubscriptionSummary{Costs: make([]model.BillingPeriodCost, len(periods))}
+
+	for i, sub := range subscriptions {
+		if i > 0 {
+			writer.WriteString("\n")
+		}
+
+		var rows []model.ResourceGroupSummary
+		for _, rg := range costs {
+			if rg.SubscriptionName == sub.Name {
+				rows = append(rows, rg)
+			}
+		}
+		sortResourceGroups(rows, tf.sortOrder, latest)
 
-	writer.WriteString(fmt.Sprintf("%-70s %-30s %-7s", strings.Repeat("=", 70), strings.Repeat("=", 30), strings.Repeat("=", 7)))
+		for _, rg := range rows {
+			writer.WriteString(fmt.Sprintf("%s %s %-7t", padText(rg.Name, nameWidth), padText(rg.SubscriptionName, parentWidth), rg.Active))
+			for p, cost := range rg.Costs {
+				value := fmt.Sprintf(" %12.2f", cost.Total)
+				if p > 0 && cost.Total > rg.Costs[p-1].Total {
+					value = tf.colourise(value, colourIncrease)
+				} else if p > 0 && cost.Total < rg.Costs[p-1].Total {
+					value = tf.colourise(value, colourDecrease)
+				}
+				writer.WriteString(value)
+			}
+			writer.WriteString(fmt.Sprintf(" %12.2f\n", rg.TotalCost))
+		}
+
+		separator("-")
+		totals("Subtotal", sub.Costs, sub.TotalCost)
 
-	for range costs[0].Costs {
-		writer.WriteString(fmt.Sprintf(" %12s", strings.Repeat("=", 12)))
+		for p, bp := range sub.Costs {
+			grandTotal.Costs[p].Total += bp.Total
+		}
+		grandTotal.TotalCost += sub.TotalCost
 	}
 
-	writer.WriteString(fmt.Sprintf(" %12s\n", strings.Repeat("=", 12)))
+	separator("=")
+	totals("Total", grandTotal.Costs, grandTotal.TotalCost)
+
+	if legend := forecastLegend(periods); len(legend) > 0 {
+		writer.WriteString(fmt.Sprintf("\n%s\n", legend))
+	}
+
+	return writer.Flush()
+}
 
+// columnWidths returns the widths of the name and parent columns, sized to their content. When writing to a terminal
+// the columns are narrowed, down to a minimum width, so that each line fits within it.
+func (tf TextFormatter) columnWidths(costs []model.ResourceGroupSummary) (int, int) {
+	nameWidth := max(utf8.RuneCountInString(tf.grouping.Name), len("Subtotal"))
+	parentWidth := utf8.RuneCountInString(tf.grouping.Parent)
 	for _, rg := range costs {
-		writer.WriteString(fmt.Sprintf("%-70s %-30s %-7t", trimValue(rg.Name, 50), trimValue(rg.SubscriptionName, 30), rg.Active))
-		for _, cost := range rg.Costs {
-			writer.WriteString(fmt.Sprintf(" %12.2f", cost.Total))
+		nameWidth = max(nameWidth, utf8.RuneCountInString(rg.Name))
+		parentWidth = max(parentWidth, utf8.RuneCountInString(rg.SubscriptionName))
+	}
+
+	if tf.width == 0 {
+		return nameWidth, parentWidth
+	}
+
+	// The active column, each billing period, and the total are a fixed width.
+	available := tf.width - 2 - 8 - (len(costs[0].Costs)+1)*13
+	for nameWidth+parentWidth > available && (nameWidth > minNameWidth || parentWidth > minParentWidth) {
+		if nameWidth > minNameWidth && (nameWidth >= parentWidth || parentWidth <= minParentWidth) {
+			nameWidth--
+		} else {
+			parentWidth--
+		}
+	}
+
+	return nameWidth, parentWidth
+}
+
+func (tf TextFormatter) colourise(value string, colour string) string {
+	if !tf.colour {
+		return value
+	}
+	return colour + value + colourReset
+}
+
+// latestPeriod returns the index of the latest billing period which is not a forecast.
+func latestPeriod(periods []model.BillingPeriodCost) int {
+	latest := 0
+	for i, bp := range periods {
+		if !bp.Forecast {
+			latest = i
+		}
+	}
+	return latest
+}
+
+// sortSubscriptions sorts the subscriptions by name, or with the highest costs first when sorting by total or by the
+// latest billing period.
+func sortSubscriptions(subscriptions []model.SubscriptionSummary, order SortOrder, latest int) {
+	slices.SortStableFunc(subscriptions, func(a, b model.SubscriptionSummary) int {
+		switch order {
+		case SortByTotal:
+			if c := cmp.Compare(b.TotalCost, a.TotalCost); c != 0 {
+				return c
+			}
+		case SortByLatest:
+			if c := cmp.Compare(b.Costs[latest].Total, a.Costs[latest].Total); c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+}
+
+func sortResourceGroups(costs []model.ResourceGroupSummary, order SortOrder, latest int) {
+	slices.SortStableFunc(costs, func(a, b model.ResourceGroupSummary) int {
+		switch order {
+		case SortByTotal:
+			if c := cmp.Compare(b.TotalCost, a.TotalCost); c != 0 {
+				return c
+			}
+		case SortByLatest:
+			if c := cmp.Compare(b.Costs[latest].Total, a.Costs[latest].Total); c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+}
+
+func (tf TextFormatter) GenerateBudgets(w io.Writer, utilisation []model.BudgetUtilisation) error {
+	writer := bufio.NewWriter(w)
+
+	writer.WriteString(fmt.Sprintf("%-50s %-7s %12s %12s %11s %12s %s\n", "Budget", "Period", "Budget", "Actual", "Utilisation", "Overrun", "Status"))
+	writer.WriteString(fmt.Sprintf("%-50s %-7s %12s %12s %11s %12s %s\n", strings.Repeat("=", 50), strings.Repeat("=", 7), strings.Repeat("=", 12), strings.Repeat("=", 12), strings.Repeat("=", 11), strings.Repeat("=", 12), strings.Repeat("=", 8)))
+
+	for _, bu := range utilisation {
+		status := "OK"
+		if bu.Breached {
+			status = "BREACHED"
 		}
-		writer.WriteString(fmt.Sprintf(" %12.2f\n", rg.TotalCost))
+
+		writer.WriteString(fmt.Sprintf("%-50s %-7s %12.2f %12.2f %10.1f%% %12.2f %s\n", trimValue(bu.Name, 50), bu.Period, bu.Amount, bu.Actual, bu.Utilisation, bu.Overrun, status))
 	}
 
-	writer.Flush()
-	return nil
+	return writer.Flush()
 }
 
 func trimValue(value string, maxLen int) string {
@@ -68,3 +239,12 @@ func trimValue(value string, maxLen int) string {
 	}
 	return value
 }
+
+// padText pads the value with spaces to the given width, truncating values which are longer and marking them with an
+// ellipsis.
+func padText(value string, width int) string {
+	if utf8.RuneCountInString(value) > width {
+		value = string([]rune(value)[:width-1]) + "…"
+	}
+	return value + strings.Repeat(" ", width-utf8.RuneCountInString(value))
+}
@@ -0,0 +1,20 @@
+package model
+
+const (
+	AnomalySpike    = "spike"
+	AnomalyIncrease = "increase"
+	AnomalyNew      = "new"
+	AnomalyVanished = "vanished"
+)
+
+type Anomaly struct {
+	Kind             string  `json:"kind"`
+	ResourceGroup    string  `json:"resourceGroup"`
+	SubscriptionName string  `json:"subscriptionName"`
+	Period           string  `json:"period"`
+	Cost             float64 `json:"cost"`
+	PreviousCost     float64 `json:"previousCost"`
+	Mean             float64 `json:"mean"`
+	StdDev           float64 `json:"stdDev"`
+	Change           float64 `json:"change"`
+}
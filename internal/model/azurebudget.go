@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type AzureBudget struct {
+	Id             string    `json:"id"`
+	Name           string    `json:"name"`
+	SubscriptionId string    `json:"subscriptionId"`
+	ResourceGroup  string    `json:"resourceGroup,omitempty"`
+	Amount         float64   `json:"amount"`
+	TimeGrain      string    `json:"timeGrain"`
+	StartDate      time.Time `json:"startDate"`
+	EndDate        time.Time `json:"endDate"`
+}
@@ -0,0 +1,24 @@
+package model
+
+type Budget struct {
+	Name          string  `json:"name"`
+	Subscription  string  `json:"subscription"`
+	ResourceGroup string  `json:"resourceGroup"`
+	Amount        float64 `json:"amount"`
+	Threshold     float64 `json:"threshold"`
+	StartPeriod   string  `json:"startPeriod,omitempty"`
+	EndPeriod     string  `json:"endPeriod,omitempty"`
+}
+
+type BudgetUtilisation struct {
+	Name          string  `json:"name"`
+	Subscription  string  `json:"subscription"`
+	ResourceGroup string  `json:"resourceGroup"`
+	Period        string  `json:"period"`
+	Amount        float64 `json:"amount"`
+	Actual        float64 `json:"actual"`
+	Utilisation   float64 `json:"utilisation"`
+	Threshold     float64 `json:"threshold"`
+	Overrun       float64 `json:"overrun"`
+	Breached      bool    `json:"breached"`
+}
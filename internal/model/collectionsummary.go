@@ -3,7 +3,8 @@ package model
 import "time"
 
 type CollectionSummary struct {
-	SubscriptionId   string
-	SubscriptionName string
-	BillingPeriod    time.Time
+	SubscriptionId   string    `json:"subscriptionId"`
+	SubscriptionName string    `json:"subscriptionName"`
+	TenantId         string    `json:"tenantId,omitempty"`
+	BillingPeriod    time.Time `json:"billingPeriod"`
 }
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type CostForecast struct {
+	SubscriptionId string    `json:"subscriptionId"`
+	BillingPeriod  time.Time `json:"billingPeriod"`
+	Cost           float64   `json:"cost"`
+	Currency       string    `json:"currency"`
+}
@@ -0,0 +1,15 @@
+package model
+
+// CostRecord is the cost of a single resource group for a single billing period, used for long-form outputs where
+// each billing period is a separate record rather than a column.
+type CostRecord struct {
+	SubscriptionId   string  `json:"subscriptionId"`
+	SubscriptionName string  `json:"subscriptionName"`
+	TenantId         string  `json:"tenantId,omitempty"`
+	ResourceGroup    string  `json:"resourceGroup"`
+	BillingPeriod    string  `json:"billingPeriod"`
+	Cost             float64 `json:"cost"`
+	CostUSD          float64 `json:"costUSD"`
+	Currency         string  `json:"currency"`
+	Active           bool    `json:"active"`
+}
@@ -1,13 +1,18 @@
 package model
 
 type BillingPeriodCost struct {
-	Period string  `json:"period"`
-	Total  float64 `json:"total"`
+	Period   string  `json:"period"`
+	Total    float64 `json:"total"`
+	Forecast bool    `json:"forecast,omitempty"`
+	Source   string  `json:"source,omitempty"`
 }
 
 type ResourceGroupSummary struct {
 	Name             string              `json:"name"`
+	SubscriptionId   string              `json:"subscriptionId"`
 	SubscriptionName string              `json:"subscriptionName"`
+	TenantId         string              `json:"tenantId,omitempty"`
+	Currency         string              `json:"currency,omitempty"`
 	Active           bool                `json:"active"`
 	Costs            []BillingPeriodCost `json:"costs"`
 	TotalCost        float64             `json:"totalCost"`
@@ -0,0 +1,13 @@
+package model
+
+const (
+	ManagementGroupType = "managementGroup"
+	SubscriptionType    = "subscription"
+)
+
+type ManagementGroupNode struct {
+	Id          string
+	DisplayName string
+	ParentId    string
+	Type        string
+}
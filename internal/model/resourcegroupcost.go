@@ -10,4 +10,6 @@ type ResourceGroupCost struct {
 	Cost             float64
 	CostUSD          float64
 	Currency         string
+	Scope            string
+	TenantId         string
 }
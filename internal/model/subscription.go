@@ -1,7 +1,7 @@
 package model
 
 type Subscription struct {
-	Id       string
-	TenantId string
-	Name     string
+	Id       string `json:"id"`
+	TenantId string `json:"tenantId,omitempty"`
+	Name     string `json:"name"`
 }
@@ -12,7 +12,14 @@ import (
 	"time"
 )
 
-const dbVersion = 1
+const dbVersion = 5
+
+var (
+	// ErrNoCostData is returned when reporting on a store which does not yet contain any costs.
+	ErrNoCostData = errors.New("no cost data has yet been collected to report on")
+	// ErrNoTenantCostData is returned when reporting on tenants for which no costs have been collected.
+	ErrNoTenantCostData = errors.New("no cost data has been collected for the selected tenants")
+)
 
 type CostManagementStore struct {
 	dbPath string
@@ -31,6 +38,18 @@ func getDatabaseVersion(db *sql.DB) (int, error) {
 	return userVersion, nil
 }
 
+func tableExists(db *sql.DB, tableName string) (bool, error) {
+	row := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", tableName)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
 func updateDbVersion1(db *sql.DB) error {
 	_, err := db.Exec(`ALTER TABLE costs ADD resource_group_status TEXT DEFAULT 'inactive';
 
@@ -39,7 +58,81 @@ func updateDbVersion1(db *sql.DB) error {
 	return err
 }
 
-// initializeDatabase initializes the database by creating the "costs" table if it doesn't exist.
+func updateDbVersion2(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE costs ADD collected_at DATETIME;
+
+	PRAGMA user_version = 2;`)
+
+	return err
+}
+
+func updateDbVersion3(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE costs ADD scope TEXT;
+
+	PRAGMA user_version = 3;`)
+
+	return err
+}
+
+func updateDbVersion4(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE costs ADD tenant_id TEXT;
+
+	PRAGMA user_version = 4;`)
+
+	return err
+}
+
+// updateDbVersion5 lower cases the subscription ids already stored, so that costs collected from Azure and imported
+// from exports are stored against the same subscription.
+func updateDbVersion5(db *sql.DB) error {
+	_, err := db.Exec(`UPDATE costs SET subscription_id = LOWER(subscription_id);
+	UPDATE budgets SET subscription_id = LOWER(subscription_id);
+	UPDATE forecasts SET subscription_id = LOWER(subscription_id);
+	UPDATE OR REPLACE management_groups SET id = LOWER(id) WHERE node_type = 'subscription';
+
+	PRAGMA user_version = 5;`)
+
+	return err
+}
+
+// createSupportingTables creates the tables which sit alongside the "costs" table if they don't already exist.
+func createSupportingTables(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS budgets
+    (
+        id TEXT
+        , name TEXT
+        , subscription_id TEXT
+        , resource_group TEXT
+        , amount REAL
+        , time_grain TEXT
+        , start_date DATETIME
+        , end_date DATETIME
+    );
+
+    CREATE TABLE IF NOT EXISTS forecasts
+    (
+        id INTEGER PRIMARY KEY AUTOINCREMENT
+        , billing_from DATETIME
+        , billing_period TEXT
+        , subscription_id TEXT
+        , cost REAL
+        , currency TEXT
+        , collected_at DATETIME
+    );
+
+    CREATE TABLE IF NOT EXISTS management_groups
+    (
+        id TEXT PRIMARY KEY
+        , display_name TEXT
+        , parent_id TEXT
+        , node_type TEXT
+    );`)
+
+	return err
+}
+
+// initializeDatabase initializes the database by creating the "costs" table if it doesn't exist, or by applying
+// any outstanding updates to an existing database.
 //
 // If an error occurs during table creation, the error is returned.
 func initializeDatabase(db *sql.DB) error {
@@ -48,6 +141,33 @@ func initializeDatabase(db *sql.DB) error {
 		return err
 	}
 
+	if err = createSupportingTables(db); err != nil {
+		return err
+	}
+
+	exists, err := tableExists(db, "costs")
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		updates := []func(*sql.DB) error{
+			updateDbVersion1,
+			updateDbVersion2,
+			updateDbVersion3,
+			updateDbVersion4,
+			updateDbVersion5,
+		}
+
+		for v := ver; v < dbVersion; v++ {
+			if err = updates[v](db); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
 	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS costs
     (
         id INTEGER PRIMARY KEY AUTOINCREMENT
@@ -60,6 +180,9 @@ func initializeDatabase(db *sql.DB) error {
         , cost READ
         , cost_usd REAL
         , currency TEXT
+        , collected_at DATETIME
+        , scope TEXT
+        , tenant_id TEXT
     );`)
 	if err != nil {
 		return err
@@ -70,13 +193,6 @@ func initializeDatabase(db *sql.DB) error {
 		return err
 	}
 
-	if ver < dbVersion {
-		err = updateDbVersion1(db)
-		if err != nil {
-			return err
-		}
-	}
-
 	return nil
 }
 
@@ -117,6 +233,9 @@ func (cm *CostManagementStore) Close() error {
 	return nil
 }
 
+// SaveCosts stores the resource group costs, marking each resource group as active or inactive depending on whether
+// it is one of the current resource groups. If the current resource groups are not known, such as when they cannot be
+// listed for a subscription, then nil should be provided and the status of each resource group is stored as unknown.
 func (cm *CostManagementStore) SaveCosts(costs []model.ResourceGroupCost, currentResourceGroups []model.ResourceGroup) error {
 	tx, err := cm.db.Begin()
 	if err != nil {
@@ -134,19 +253,26 @@ func (cm *CostManagementStore) SaveCosts(costs []model.ResourceGroupCost, curren
 			, cost
 			, cost_usd
 			, currency
+			, collected_at
+			, scope
+			, tenant_id
 		)
 		VALUES
 		(
-			?, ?, ?, ?, ?, ?, ?, ?, ?)
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	collectedAt := time.Now().UTC()
+
 	for _, cost := range costs {
 		status := "inactive"
-		if slices.ContainsFunc(currentResourceGroups, func(rg model.ResourceGroup) bool {
+		if currentResourceGroups == nil {
+			status = "unknown"
+		} else if slices.ContainsFunc(currentResourceGroups, func(rg model.ResourceGroup) bool {
 			return cost.Name == rg.Name
 		}) {
 			status = "active"
@@ -158,10 +284,13 @@ func (cm *CostManagementStore) SaveCosts(costs []model.ResourceGroupCost, curren
 			cost.Name,
 			status,
 			cost.SubscriptionName,
-			cost.SubscriptionId,
+			normaliseSubscriptionId(cost.SubscriptionId),
 			cost.Cost,
 			cost.CostUSD,
-			cost.Currency)
+			cost.Currency,
+			collectedAt,
+			cost.Scope,
+			cost.TenantId)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -177,55 +306,75 @@ func (cm *CostManagementStore) SaveCosts(costs []model.ResourceGroupCost, curren
 	return nil
 }
 
-func (cm *CostManagementStore) createSummaryView(billingPeriods []string) error {
+// summaryQuery returns the query which summarises the cost of each resource group with a column for each billing
+// period, along with its arguments. The query is run directly rather than through a view so that summaries can be
+// generated concurrently, and without writing to the store.
+func summaryQuery(billingPeriods []string, tenants []string) (string, []any) {
+	var args []any
+
 	queryBuilder := strings.Builder{}
-	queryBuilder.WriteString("DROP VIEW IF EXISTS vw_cost_summary;")
-	queryBuilder.WriteString("CREATE VIEW vw_cost_summary AS\n")
-	queryBuilder.WriteString("SELECT resource_group AS `ResourceGroup`, subscription_name AS `Subscription`\n")
+	queryBuilder.WriteString("SELECT resource_group AS `ResourceGroup`, subscription_id AS `SubscriptionId`, subscription_name AS `Subscription`\n")
+	queryBuilder.WriteString("    , COALESCE(MAX(tenant_id), '') AS `TenantId`, COALESCE(MAX(currency), '') AS `Currency`\n")
 	queryBuilder.WriteString("    , CASE WHEN current_status = 'active' THEN 1 ELSE 0 END AS 'Active'\n")
 
 	for _, bp := range billingPeriods {
-		queryBuilder.WriteString(fmt.Sprintf(", SUM(cost) filter (where billing_period = '%[1]s') AS `%[1]s`\n", bp))
+		queryBuilder.WriteString(", SUM(cost) filter (where billing_period = ?)\n")
+		args = append(args, bp)
 	}
 
 	queryBuilder.WriteString(", SUM(cost) AS `TotalCost`\n")
 	queryBuilder.WriteString("FROM (\n")
-	queryBuilder.WriteString("    SELECT resource_group, subscription_id, subscription_name, resource_group_status, cost, billing_period\n")
+	queryBuilder.WriteString("    SELECT resource_group, subscription_id, subscription_name, tenant_id, currency, resource_group_status, cost, billing_period\n")
 	queryBuilder.WriteString("           , LAST_VALUE(resource_group_status) OVER (PARTITION BY subscription_id, resource_group ORDER BY billing_from RANGE BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING) AS `current_status`\n")
 	queryBuilder.WriteString("    FROM costs\n")
-	queryBuilder.WriteString("    WHERE billing_period IN (")
+	queryBuilder.WriteString("    WHERE billing_period IN (?")
+	queryBuilder.WriteString(strings.Repeat(", ?", len(billingPeriods)-1))
+	queryBuilder.WriteString(")\n")
+	for _, bp := range billingPeriods {
+		args = append(args, bp)
+	}
 
-	for i, bp := range billingPeriods {
-		if i > 0 {
-			queryBuilder.WriteString(", ")
+	if len(tenants) > 0 {
+		queryBuilder.WriteString("    AND LOWER(tenant_id) IN (?")
+		queryBuilder.WriteString(strings.Repeat(", ?", len(tenants)-1))
+		queryBuilder.WriteString(")\n")
+		for _, tenant := range tenants {
+			args = append(args, strings.ToLower(tenant))
 		}
-		queryBuilder.WriteString(fmt.Sprintf("'%s'", bp))
 	}
 
 	queryBuilder.WriteString(")\n")
-	queryBuilder.WriteString(")\n")
-	queryBuilder.WriteString("GROUP BY resource_group, subscription_name;\n")
+	queryBuilder.WriteString("GROUP BY resource_group, subscription_id, subscription_name\n")
+	queryBuilder.WriteString("ORDER BY resource_group\n")
 
-	_, err := cm.db.Exec(queryBuilder.String())
-	return err
+	return queryBuilder.String(), args
 }
 
-func (cm *CostManagementStore) GenerateSummaryByResourceGroup(months int) ([]model.ResourceGroupSummary, error) {
+// GenerateSummaryByResourceGroup summarises the costs of each resource group over the given number of months. When
+// tenants are provided only the costs of subscriptions in those tenants are included.
+func (cm *CostManagementStore) GenerateSummaryByResourceGroup(months int, tenants []string) ([]model.ResourceGroupSummary, error) {
 	billingPeriods, err := cm.GetAllBillingPeriods(months)
 	if err != nil {
 		return nil, err
 	}
 
-	err = cm.createSummaryView(billingPeriods)
+	if len(billingPeriods) == 0 && len(tenants) > 0 {
+		return nil, ErrNoTenantCostData
+	} else if len(billingPeriods) == 0 {
+		return nil, ErrNoCostData
+	}
+
+	query, args := summaryQuery(billingPeriods, tenants)
+	rows, err := cm.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := cm.db.Query("SELECT * FROM vw_cost_summary ORDER BY ResourceGroup")
+	defer rows.Close()
+
+	cols, err := rows.Columns()
 	if err != nil {
 		return nil, err
 	}
-
-	cols, _ := rows.Columns()
 	row := make([]any, len(cols))
 	rowPtr := make([]any, len(cols))
 	for i := range row {
@@ -234,35 +383,332 @@ func (cm *CostManagementStore) GenerateSummaryByResourceGroup(months int) ([]mod
 
 	var summary []model.ResourceGroupSummary
 	for rows.Next() {
-		_ = rows.Scan(rowPtr...)
+		if err := rows.Scan(rowPtr...); err != nil {
+			return nil, err
+		}
 		groupBillingCosts := make([]model.BillingPeriodCost, 0, len(billingPeriods))
-		for i := 3; i < len(row)-1; i++ {
+		for i, bp := range billingPeriods {
 			groupBillingCosts = append(groupBillingCosts, model.BillingPeriodCost{
-				Period: cols[i],
-				Total:  costToFloat(row[i]),
+				Period: bp,
+				Total:  costToFloat(row[i+6]),
 			})
 		}
 
 		summary = append(summary, model.ResourceGroupSummary{
 			Name:             row[0].(string),
-			SubscriptionName: row[1].(string),
-			Active:           row[2].(int64) == 1,
+			SubscriptionId:   row[1].(string),
+			SubscriptionName: row[2].(string),
+			TenantId:         row[3].(string),
+			Currency:         row[4].(string),
+			Active:           row[5].(int64) == 1,
 			Costs:            groupBillingCosts,
 			TotalCost:        costToFloat(row[len(row)-1]),
 		})
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	if summary == nil {
-		return nil, fmt.Errorf("no cost data has yet been collected to report on")
+	if summary == nil && len(tenants) > 0 {
+		return nil, ErrNoTenantCostData
+	} else if summary == nil {
+		return nil, ErrNoCostData
 	}
 
 	return summary, nil
 }
 
+// GenerateSummaryByManagementGroup summarises the costs of each subscription under every management group above it,
+// using the management group hierarchy collected with the costs, so that the costs of a management group include
+// those of its descendants. Each summary uses the management group display name as its name, and the display name of
+// its parent management group in place of the subscription name. Management groups are identified by their id, so
+// groups which share a display name are summarised separately. Costs
+// billed in different currencies are not added together, instead each currency is summarised separately with the
+// currency added to the name. Costs for subscriptions which are not part of a collected hierarchy are summarised as
+// "(Unassigned)".
+func (cm *CostManagementStore) GenerateSummaryByManagementGroup(months int, tenants []string) ([]model.ResourceGroupSummary, error) {
+	summary, err := cm.GenerateSummaryByResourceGroup(months, tenants)
+	if err != nil {
+		return nil, err
+	}
+
+	hierarchy, err := cm.GetManagementGroupHierarchy()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]model.ManagementGroupNode)
+	for _, node := range hierarchy {
+		nodes[strings.ToLower(node.Id)] = node
+	}
+
+	ancestors, err := cm.getManagementGroupAncestors()
+	if err != nil {
+		return nil, err
+	}
+
+	type groupKey struct {
+		id       string
+		currency string
+	}
+
+	groups := make(map[groupKey]*model.ResourceGroupSummary)
+	var groupKeys []groupKey
+
+	addCosts := func(key groupKey, name string, parentName string, rg model.ResourceGroupSummary) {
+		group, ok := groups[key]
+		if !ok {
+			group = &model.ResourceGroupSummary{
+				Name:             name,
+				SubscriptionName: parentName,
+				Currency:         rg.Currency,
+				Costs:            make([]model.BillingPeriodCost, len(rg.Costs)),
+			}
+			for i, bp := range rg.Costs {
+				group.Costs[i].Period = bp.Period
+			}
+			groups[key] = group
+			groupKeys = append(groupKeys, key)
+		}
+
+		group.Active = group.Active || rg.Active
+		group.TotalCost += rg.TotalCost
+		for i, bp := range rg.Costs {
+			group.Costs[i].Total += bp.Total
+		}
+	}
+
+	for _, rg := range summary {
+		groupIds := ancestors[strings.ToLower(rg.SubscriptionId)]
+		if len(groupIds) == 0 {
+			addCosts(groupKey{currency: rg.Currency}, "(Unassigned)", "", rg)
+			continue
+		}
+
+		for _, id := range groupIds {
+			mg := nodes[id]
+			addCosts(groupKey{id: id, currency: rg.Currency}, mg.DisplayName, nodes[strings.ToLower(mg.ParentId)].DisplayName, rg)
+		}
+	}
+
+	currencies := make(map[string]int)
+	for _, key := range groupKeys {
+		currencies[key.id]++
+	}
+	for _, key := range groupKeys {
+		if currencies[key.id] > 1 && len(key.currency) > 0 {
+			groups[key].Name = fmt.Sprintf("%s (%s)", groups[key].Name, key.currency)
+		}
+	}
+
+	slices.SortFunc(groupKeys, func(a, b groupKey) int {
+		if c := strings.Compare(groups[a].Name, groups[b].Name); c != 0 {
+			return c
+		}
+		if c := strings.Compare(groups[a].SubscriptionName, groups[b].SubscriptionName); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.id, b.id); c != 0 {
+			return c
+		}
+		return strings.Compare(a.currency, b.currency)
+	})
+
+	groupSummary := make([]model.ResourceGroupSummary, len(groupKeys))
+	for i, key := range groupKeys {
+		groupSummary[i] = *groups[key]
+	}
+
+	return groupSummary, nil
+}
+
+// getManagementGroupAncestors returns the ids of the collected management groups above each subscription, keyed by
+// the subscription id. Ids are compared and returned in lower case as Azure does not use a consistent case for them.
+func (cm *CostManagementStore) getManagementGroupAncestors() (map[string][]string, error) {
+	rows, err := cm.db.Query(`
+		WITH RECURSIVE ancestors (subscription_id, group_id) AS (
+			SELECT LOWER(id), LOWER(parent_id)
+			FROM management_groups
+			WHERE node_type = ?
+			UNION
+			SELECT ancestors.subscription_id, LOWER(mg.parent_id)
+			FROM ancestors
+			INNER JOIN management_groups AS mg ON LOWER(mg.id) = ancestors.group_id AND mg.node_type = ?
+		)
+		SELECT ancestors.subscription_id, ancestors.group_id
+		FROM ancestors
+		INNER JOIN management_groups AS mg ON LOWER(mg.id) = ancestors.group_id AND mg.node_type = ?
+		ORDER BY ancestors.subscription_id, ancestors.group_id`,
+		model.SubscriptionType, model.ManagementGroupType, model.ManagementGroupType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ancestors := make(map[string][]string)
+	for rows.Next() {
+		var subscriptionId, groupId string
+		err := rows.Scan(&subscriptionId, &groupId)
+		if err != nil {
+			return nil, err
+		}
+		ancestors[subscriptionId] = append(ancestors[subscriptionId], groupId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ancestors, nil
+}
+
+// GenerateSummaryByTenant summarises the costs of each subscription, using the subscription name as the name of each
+// summary and the id of the tenant the subscription belongs to in place of the subscription name. Costs for
+// subscriptions which were collected before tenants were recorded are summarised under "(Unknown)".
+func (cm *CostManagementStore) GenerateSummaryByTenant(months int, tenants []string) ([]model.ResourceGroupSummary, error) {
+	summary, err := cm.GenerateSummaryByResourceGroup(months, tenants)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make(map[string]*model.ResourceGroupSummary)
+	var subscriptionIds []string
+
+	for _, rg := range summary {
+		sub, ok := subscriptions[rg.SubscriptionId]
+		if !ok {
+			tenant := rg.TenantId
+			if len(tenant) == 0 {
+				tenant = "(Unknown)"
+			}
+
+			sub = &model.ResourceGroupSummary{
+				Name:             rg.SubscriptionName,
+				SubscriptionId:   rg.SubscriptionId,
+				SubscriptionName: tenant,
+				TenantId:         rg.TenantId,
+				Currency:         rg.Currency,
+				Costs:            make([]model.BillingPeriodCost, len(rg.Costs)),
+			}
+			for i, bp := range rg.Costs {
+				sub.Costs[i].Period = bp.Period
+			}
+			subscriptions[rg.SubscriptionId] = sub
+			subscriptionIds = append(subscriptionIds, rg.SubscriptionId)
+		}
+
+		sub.Active = sub.Active || rg.Active
+		sub.TotalCost += rg.TotalCost
+		for i, bp := range rg.Costs {
+			sub.Costs[i].Total += bp.Total
+		}
+	}
+
+	tenantSummary := make([]model.ResourceGroupSummary, len(subscriptionIds))
+	for i, id := range subscriptionIds {
+		tenantSummary[i] = *subscriptions[id]
+	}
+
+	slices.SortFunc(tenantSummary, func(a, b model.ResourceGroupSummary) int {
+		if c := strings.Compare(a.SubscriptionName, b.SubscriptionName); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return tenantSummary, nil
+}
+
+// StreamCosts reads the cost of each resource group for each billing period over the given number of months, or over
+// every collected billing period when months is 0, calling the provided function with each record as it is read
+// rather than loading the costs into memory. When tenants are provided only the costs of subscriptions in those tenants
+// are included. The active status of each record is the current status of the resource group, in the same way as the
+// summaries.
+func (cm *CostManagementStore) StreamCosts(months int, tenants []string, fn func(model.CostRecord) error) error {
+	query := strings.Builder{}
+	query.WriteString(`
+		SELECT
+			subscription_id
+			, subscription_name
+			, COALESCE(MAX(tenant_id), '')
+			, resource_group
+			, billing_period
+			, SUM(cost)
+			, SUM(cost_usd)
+			, COALESCE(MAX(currency), '')
+			, MAX(CASE WHEN current_status = 'active' THEN 1 ELSE 0 END)
+		FROM (
+			SELECT subscription_id, subscription_name, tenant_id, resource_group, billing_period, cost, cost_usd, currency
+				, LAST_VALUE(resource_group_status) OVER (PARTITION BY subscription_id, resource_group ORDER BY billing_from RANGE BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING) AS current_status
+			FROM costs
+			WHERE 1 = 1`)
+
+	var args []any
+	if months > 0 {
+		query.WriteString(" AND billing_from > ?")
+		args = append(args, billingFromDate(months))
+	}
+	if len(tenants) > 0 {
+		query.WriteString(" AND LOWER(tenant_id) IN (?")
+		query.WriteString(strings.Repeat(", ?", len(tenants)-1))
+		query.WriteString(")")
+		for _, tenant := range tenants {
+			args = append(args, strings.ToLower(tenant))
+		}
+	}
+
+	query.WriteString(`
+		)
+		GROUP BY
+			subscription_id
+			, subscription_name
+			, resource_group
+			, billing_period
+		ORDER BY
+			subscription_name
+			, resource_group
+			, billing_period`)
+
+	rows, err := cm.db.Query(query.String(), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var record model.CostRecord
+		var cost, costUSD any
+		var active int64
+		err := rows.Scan(&record.SubscriptionId, &record.SubscriptionName, &record.TenantId, &record.ResourceGroup,
+			&record.BillingPeriod, &cost, &costUSD, &record.Currency, &active)
+		if err != nil {
+			return err
+		}
+
+		record.Cost = costToFloat(cost)
+		record.CostUSD = costToFloat(costUSD)
+		record.Active = active == 1
+
+		if err := fn(record); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if count == 0 && len(tenants) > 0 {
+		return ErrNoTenantCostData
+	} else if count == 0 {
+		return ErrNoCostData
+	}
+
+	return nil
+}
+
 func (cm *CostManagementStore) GetAllBillingPeriods(months int) ([]string, error) {
-	fromDate := time.Now().UTC().AddDate(0, months*-1, 0)
-	fromDate = fromDate.AddDate(0, 0, -fromDate.Day()+1).Add(time.Minute)
-	rows, err := cm.db.Query("SELECT DISTINCT billing_period FROM costs WHERE billing_from > ? ORDER BY billing_period", fromDate)
+	rows, err := cm.db.Query("SELECT DISTINCT billing_period FROM costs WHERE billing_from > ? ORDER BY billing_period", billingFromDate(months))
 	if err != nil {
 		return nil, err
 	}
@@ -282,7 +728,7 @@ func (cm *CostManagementStore) GetAllBillingPeriods(months int) ([]string, error
 }
 
 func (cm *CostManagementStore) GetSubscriptionBillingPeriods(subscriptionId string) ([]string, error) {
-	rows, err := cm.db.Query("SELECT DISTINCT billing_period FROM costs WHERE subscription_id = ? ORDER BY billing_period", subscriptionId)
+	rows, err := cm.db.Query("SELECT DISTINCT billing_period FROM costs WHERE subscription_id = ? ORDER BY billing_period", normaliseSubscriptionId(subscriptionId))
 	if err != nil {
 		return nil, err
 	}
@@ -306,6 +752,7 @@ func (cm *CostManagementStore) GetCollectionSummary() ([]model.CollectionSummary
 		SELECT
 			subscription_name
 			, subscription_id
+			, COALESCE(MAX(tenant_id), '') AS tenant_id
 			, billing_from
 		FROM
 			costs
@@ -314,27 +761,66 @@ func (cm *CostManagementStore) GetCollectionSummary() ([]model.CollectionSummary
 			, subscription_id
 			, billing_from
 		ORDER BY
-			subscription_name
+			tenant_id
+			, subscription_name
 			, billing_from DESC`)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var collectionSummaries []model.CollectionSummary
 	for rows.Next() {
 		var summary model.CollectionSummary
-		err := rows.Scan(&summary.SubscriptionName, &summary.SubscriptionId, &summary.BillingPeriod)
+		err := rows.Scan(&summary.SubscriptionName, &summary.SubscriptionId, &summary.TenantId, &summary.BillingPeriod)
 		if err != nil {
 			return nil, err
 		}
 		collectionSummaries = append(collectionSummaries, summary)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
 	return collectionSummaries, nil
 }
 
+// GetCollectionTimes returns the time at which each subscription was last collected for the given billing period,
+// keyed by subscription id. Data collected before collection times were recorded is not included.
+func (cm *CostManagementStore) GetCollectionTimes(billingPeriod string) (map[string]time.Time, error) {
+	rows, err := cm.db.Query(`
+		SELECT DISTINCT
+			subscription_id
+			, collected_at
+		FROM
+			costs
+		WHERE
+			billing_period = ?
+			AND collected_at IS NOT NULL`, billingPeriod)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collectionTimes := make(map[string]time.Time)
+	for rows.Next() {
+		var subscriptionId string
+		var collectedAt time.Time
+		err := rows.Scan(&subscriptionId, &collectedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if collectedAt.After(collectionTimes[subscriptionId]) {
+			collectionTimes[subscriptionId] = collectedAt
+		}
+	}
+
+	return collectionTimes, nil
+}
+
 func (cm *CostManagementStore) DeleteSubscriptionBillingPeriod(subscriptionId string, billingPeriod string) error {
-	_, err := cm.db.Exec("DELETE FROM costs WHERE subscription_id = ? AND billing_period = ?", subscriptionId, billingPeriod)
+	_, err := cm.db.Exec("DELETE FROM costs WHERE subscription_id = ? AND billing_period = ?", normaliseSubscriptionId(subscriptionId), billingPeriod)
 	if err != nil {
 		return err
 	}
@@ -342,7 +828,11 @@ func (cm *CostManagementStore) DeleteSubscriptionBillingPeriod(subscriptionId st
 }
 
 func (cm *CostManagementStore) ListCollectedSubscriptions() ([]model.Subscription, error) {
-	rows, err := cm.db.Query("SELECT DISTINCT subscription_id, subscription_name FROM costs ORDER BY subscription_name")
+	rows, err := cm.db.Query(`
+		SELECT subscription_id, subscription_name, COALESCE(MAX(tenant_id), '')
+		FROM costs
+		GROUP BY subscription_id, subscription_name
+		ORDER BY subscription_name`)
 	if err != nil {
 		return nil, err
 	}
@@ -351,7 +841,7 @@ func (cm *CostManagementStore) ListCollectedSubscriptions() ([]model.Subscriptio
 	var subscriptions []model.Subscription
 	for rows.Next() {
 		var subscription model.Subscription
-		err := rows.Scan(&subscription.Id, &subscription.Name)
+		err := rows.Scan(&subscription.Id, &subscription.Name, &subscription.TenantId)
 		if err != nil {
 			return nil, err
 		}
@@ -361,6 +851,281 @@ func (cm *CostManagementStore) ListCollectedSubscriptions() ([]model.Subscriptio
 	return subscriptions, nil
 }
 
+// SaveBudgets replaces the budgets stored for a subscription, including those of its resource groups, with the
+// budgets provided.
+func (cm *CostManagementStore) SaveBudgets(subscriptionId string, budgets []model.AzureBudget) error {
+	subscriptionId = normaliseSubscriptionId(subscriptionId)
+
+	tx, err := cm.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM budgets WHERE subscription_id = ?", subscriptionId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO budgets
+		(
+			id
+			, name
+			, subscription_id
+			, resource_group
+			, amount
+			, time_grain
+			, start_date
+			, end_date
+		)
+		VALUES
+		(
+			?, ?, ?, ?, ?, ?, ?, ?)
+		`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, budget := range budgets {
+		_, err := stmt.Exec(
+			budget.Id,
+			budget.Name,
+			subscriptionId,
+			budget.ResourceGroup,
+			budget.Amount,
+			budget.TimeGrain,
+			budget.StartDate,
+			budget.EndDate)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// ListBudgets returns all budgets which have been collected from Azure.
+func (cm *CostManagementStore) ListBudgets() ([]model.AzureBudget, error) {
+	rows, err := cm.db.Query(`
+		SELECT
+			id
+			, name
+			, subscription_id
+			, resource_group
+			, amount
+			, time_grain
+			, start_date
+			, end_date
+		FROM
+			budgets
+		ORDER BY
+			subscription_id
+			, resource_group
+			, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []model.AzureBudget
+	for rows.Next() {
+		var budget model.AzureBudget
+		err := rows.Scan(
+			&budget.Id,
+			&budget.Name,
+			&budget.SubscriptionId,
+			&budget.ResourceGroup,
+			&budget.Amount,
+			&budget.TimeGrain,
+			&budget.StartDate,
+			&budget.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+
+	return budgets, nil
+}
+
+// SaveForecasts replaces the forecasts stored for a subscription with the forecasts provided. Forecasts are stored
+// separately from the actual costs.
+func (cm *CostManagementStore) SaveForecasts(subscriptionId string, forecasts []model.CostForecast) error {
+	subscriptionId = normaliseSubscriptionId(subscriptionId)
+
+	tx, err := cm.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM forecasts WHERE subscription_id = ?", subscriptionId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO forecasts
+		(
+			billing_from
+			, billing_period
+			, subscription_id
+			, cost
+			, currency
+			, collected_at
+		)
+		VALUES
+		(
+			?, ?, ?, ?, ?, ?)
+		`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	collectedAt := time.Now().UTC()
+
+	for _, forecast := range forecasts {
+		_, err := stmt.Exec(
+			forecast.BillingPeriod,
+			forecast.BillingPeriod.Format("2006-01"),
+			subscriptionId,
+			forecast.Cost,
+			forecast.Currency,
+			collectedAt)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// ListForecasts returns the collected forecasts for billing periods on or after the given billing period.
+func (cm *CostManagementStore) ListForecasts(fromBillingPeriod string) ([]model.CostForecast, error) {
+	rows, err := cm.db.Query(`
+		SELECT
+			subscription_id
+			, billing_from
+			, cost
+			, currency
+		FROM
+			forecasts
+		WHERE
+			billing_period >= ?
+		ORDER BY
+			subscription_id
+			, billing_from`, fromBillingPeriod)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var forecasts []model.CostForecast
+	for rows.Next() {
+		var forecast model.CostForecast
+		err := rows.Scan(&forecast.SubscriptionId, &forecast.BillingPeriod, &forecast.Cost, &forecast.Currency)
+		if err != nil {
+			return nil, err
+		}
+		forecasts = append(forecasts, forecast)
+	}
+
+	return forecasts, nil
+}
+
+// SaveManagementGroupHierarchy stores the management groups and subscriptions of a hierarchy, replacing any existing
+// entries for the same management groups and subscriptions.
+func (cm *CostManagementStore) SaveManagementGroupHierarchy(nodes []model.ManagementGroupNode) error {
+	tx, err := cm.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO management_groups
+		(
+			id
+			, display_name
+			, parent_id
+			, node_type
+		)
+		VALUES
+		(
+			?, ?, ?, ?)
+		`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, node := range nodes {
+		if node.Type == model.SubscriptionType {
+			node.Id = normaliseSubscriptionId(node.Id)
+		}
+		_, err := stmt.Exec(node.Id, node.DisplayName, node.ParentId, node.Type)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// GetManagementGroupHierarchy returns all management groups and subscriptions of the collected hierarchies.
+func (cm *CostManagementStore) GetManagementGroupHierarchy() ([]model.ManagementGroupNode, error) {
+	rows, err := cm.db.Query("SELECT id, display_name, parent_id, node_type FROM management_groups ORDER BY display_name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []model.ManagementGroupNode
+	for rows.Next() {
+		var node model.ManagementGroupNode
+		err := rows.Scan(&node.Id, &node.DisplayName, &node.ParentId, &node.Type)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// normaliseSubscriptionId returns the subscription id in the form it is stored in. Azure does not use a consistent
+// case for subscription ids, so they are stored in lower case to match costs from different sources.
+func normaliseSubscriptionId(subscriptionId string) string {
+	return strings.ToLower(subscriptionId)
+}
+
+// billingFromDate returns the date after which billing periods are included when reporting over the given number of
+// months.
+func billingFromDate(months int) time.Time {
+	fromDate := time.Now().UTC().AddDate(0, months*-1, 0)
+	return fromDate.AddDate(0, 0, -fromDate.Day()+1).Add(time.Minute)
+}
+
 func costToFloat(value interface{}) float64 {
 	switch value.(type) {
 	case int8:
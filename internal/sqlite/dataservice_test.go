@@ -0,0 +1,232 @@
+package sqlite
+
+import (
+	"github.com/dazfuller/azcosts/internal/model"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestStore returns an empty store in a temporary directory.
+func newTestStore(t *testing.T) *CostManagementStore {
+	t.Helper()
+
+	db, err := NewCostManagementStore(filepath.Join(t.TempDir(), "costs.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+// currentPeriod returns the start of the current billing period, so that costs are included in summaries.
+func currentPeriod() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestImportOverCollectedCosts(t *testing.T) {
+	const collectedId = "AAAAAAAA-0000-0000-0000-000000000001"
+	const importedId = "aaaaaaaa-0000-0000-0000-000000000001"
+	period := currentPeriod()
+
+	db := newTestStore(t)
+
+	collected := []model.ResourceGroupCost{
+		{SubscriptionId: collectedId, SubscriptionName: "Production", Name: "rg-web", BillingPeriod: period, Cost: 10, Currency: "GBP"},
+	}
+	if err := db.SaveCosts(collected, []model.ResourceGroup{{Name: "rg-web"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Imports replace the billing periods which have already been collected for the subscription
+	periods, err := db.GetSubscriptionBillingPeriods(importedId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 1 || periods[0] != period.Format("2006-01") {
+		t.Fatalf("GetSubscriptionBillingPeriods() = %v, want [%s]", periods, period.Format("2006-01"))
+	}
+	if err := db.DeleteSubscriptionBillingPeriod(importedId, periods[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := []model.ResourceGroupCost{
+		{SubscriptionId: importedId, SubscriptionName: "Production", Name: "rg-web", BillingPeriod: period, Cost: 12, Currency: "GBP"},
+	}
+	if err := db.SaveCosts(imported, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	subscriptions, err := db.ListCollectedSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscriptions) != 1 || subscriptions[0].Id != importedId {
+		t.Errorf("ListCollectedSubscriptions() = %+v, want a single subscription with id %s", subscriptions, importedId)
+	}
+
+	summary, err := db.GenerateSummaryByResourceGroup(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary) != 1 || summary[0].TotalCost != 12 {
+		t.Errorf("GenerateSummaryByResourceGroup() = %+v, want a single resource group costing 12", summary)
+	}
+}
+
+func TestSaveNormalisesSubscriptionIds(t *testing.T) {
+	const upperId = "AAAAAAAA-0000-0000-0000-000000000001"
+	const lowerId = "aaaaaaaa-0000-0000-0000-000000000001"
+	period := currentPeriod()
+
+	db := newTestStore(t)
+
+	for _, id := range []string{upperId, lowerId} {
+		if err := db.SaveBudgets(id, []model.AzureBudget{{Id: "/budgets/" + id, Name: "monthly", Amount: 100}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SaveForecasts(id, []model.CostForecast{{BillingPeriod: period, Cost: 50, Currency: "GBP"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	budgets, err := db.ListBudgets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(budgets) != 1 || budgets[0].SubscriptionId != lowerId {
+		t.Errorf("ListBudgets() = %+v, want a single budget for %s", budgets, lowerId)
+	}
+
+	forecasts, err := db.ListForecasts(period.Format("2006-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forecasts) != 1 || forecasts[0].SubscriptionId != lowerId {
+		t.Errorf("ListForecasts() = %+v, want a single forecast for %s", forecasts, lowerId)
+	}
+
+	err = db.SaveManagementGroupHierarchy([]model.ManagementGroupNode{
+		{Id: "/providers/Microsoft.Management/managementGroups/Platform", DisplayName: "Platform", Type: model.ManagementGroupType},
+		{Id: upperId, DisplayName: "Production", ParentId: "/providers/Microsoft.Management/managementGroups/Platform", Type: model.SubscriptionType},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hierarchy, err := db.GetManagementGroupHierarchy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]bool{}
+	for _, node := range hierarchy {
+		ids[node.Id] = true
+	}
+	if !ids[lowerId] || !ids["/providers/Microsoft.Management/managementGroups/Platform"] {
+		t.Errorf("GetManagementGroupHierarchy() = %+v, want the subscription id in lower case", hierarchy)
+	}
+}
+
+func TestUpdateDbVersion5(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "costs.db")
+
+	db, err := NewCostManagementStore(dbPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.db.Exec(`INSERT INTO costs (billing_period, resource_group, subscription_id, cost) VALUES
+		('2024-03', 'rg-web', 'AAAAAAAA-0000-0000-0000-000000000001', 10);
+	INSERT INTO management_groups (id, display_name, parent_id, node_type) VALUES
+		('AAAAAAAA-0000-0000-0000-000000000001', 'Production', 'mg', 'subscription'),
+		('aaaaaaaa-0000-0000-0000-000000000001', 'Production', 'mg', 'subscription'),
+		('MG', 'Platform', '', 'managementGroup');
+
+	PRAGMA user_version = 4;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewCostManagementStore(dbPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	version, err := getDatabaseVersion(db.db)
+	if err != nil || version != dbVersion {
+		t.Fatalf("getDatabaseVersion() = %d, %v, want %d", version, err, dbVersion)
+	}
+
+	periods, err := db.GetSubscriptionBillingPeriods("aaaaaaaa-0000-0000-0000-000000000001")
+	if err != nil || len(periods) != 1 {
+		t.Errorf("GetSubscriptionBillingPeriods() = %v, %v, want the collected billing period", periods, err)
+	}
+
+	hierarchy, err := db.GetManagementGroupHierarchy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, node := range hierarchy {
+		ids = append(ids, node.Id)
+	}
+	if len(ids) != 2 || ids[0] != "MG" || ids[1] != "aaaaaaaa-0000-0000-0000-000000000001" {
+		t.Errorf("GetManagementGroupHierarchy() ids = %v, want [MG aaaaaaaa-0000-0000-0000-000000000001]", ids)
+	}
+}
+
+func TestGenerateSummaryByManagementGroup(t *testing.T) {
+	period := currentPeriod()
+
+	db := newTestStore(t)
+
+	err := db.SaveManagementGroupHierarchy([]model.ManagementGroupNode{
+		{Id: "root", DisplayName: "Root", ParentId: "tenant", Type: model.ManagementGroupType},
+		{Id: "platform", DisplayName: "Platform", ParentId: "ROOT", Type: model.ManagementGroupType},
+		{Id: "00000000-0000-0000-0000-000000000001", DisplayName: "Production", ParentId: "Platform", Type: model.SubscriptionType},
+		{Id: "00000000-0000-0000-0000-000000000002", DisplayName: "Sandbox", ParentId: "root", Type: model.SubscriptionType},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	costs := []model.ResourceGroupCost{
+		{SubscriptionId: "00000000-0000-0000-0000-000000000001", SubscriptionName: "Production", Name: "rg-web", BillingPeriod: period, Cost: 10, Currency: "GBP"},
+		{SubscriptionId: "00000000-0000-0000-0000-000000000002", SubscriptionName: "Sandbox", Name: "rg-test", BillingPeriod: period, Cost: 5, Currency: "GBP"},
+		{SubscriptionId: "00000000-0000-0000-0000-000000000003", SubscriptionName: "Other", Name: "rg-other", BillingPeriod: period, Cost: 1, Currency: "GBP"},
+	}
+	if err := db.SaveCosts(costs, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := db.GenerateSummaryByManagementGroup(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type group struct {
+		name   string
+		parent string
+		total  float64
+	}
+	want := []group{
+		{name: "(Unassigned)", total: 1},
+		{name: "Platform", parent: "Root", total: 10},
+		{name: "Root", total: 15},
+	}
+
+	if len(summary) != len(want) {
+		t.Fatalf("GenerateSummaryByManagementGroup() = %+v, want %+v", summary, want)
+	}
+	for i, w := range want {
+		got := group{name: summary[i].Name, parent: summary[i].SubscriptionName, total: summary[i].TotalCost}
+		if got != w {
+			t.Errorf("GenerateSummaryByManagementGroup() group %d = %+v, want %+v", i, got, w)
+		}
+	}
+}